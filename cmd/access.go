@@ -0,0 +1,373 @@
+package cmd
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/gfaivre/ktools/internal/logging"
+	"github.com/gfaivre/ktools/pkg/kdrive"
+	"github.com/spf13/cobra"
+)
+
+var (
+	accessFormat    string
+	accessDiverging bool
+)
+
+// walkTargets walks the directory targets in a single walk (--max-depth,
+// --exclude), calling fn for every entry found. with requests extra fields.
+// Unlistable directories are reported unless --strict is set.
+func walkTargets(ctx context.Context, client *kdrive.Client, targets []*kdrive.File, with []string, fn func(*kdrive.File)) error {
+	roots := walkRoots(targets)
+	if len(roots) == 0 {
+		return nil
+	}
+
+	opts := walkOptions()
+	opts.With = with
+	opts.State = &kdrive.WalkState{Pending: roots}
+	opts.Strict = strict
+	err := client.Walk(ctx, roots[0].ID, roots[0].Name, scanProgress, opts, func(f *kdrive.File) error {
+		file := *f
+		fn(&file)
+		return nil
+	})
+	fmt.Fprintln(os.Stderr)
+
+	var partial *kdrive.PartialError
+	if errors.As(err, &partial) {
+		printPartialError(partial)
+		fmt.Fprintln(os.Stderr)
+		return nil
+	}
+	return err
+}
+
+// fetchAccess reads the access of the given entries and of their parents
+// (baseline of what they inherit), tolerating entries whose access cannot be read
+func fetchAccess(ctx context.Context, client *kdrive.Client, entries []*kdrive.File) (map[int]*kdrive.FileAccess, error) {
+	ids := make([]int, 0, len(entries))
+	listed := make(map[int]bool, len(entries))
+	for _, e := range entries {
+		if !listed[e.ID] {
+			ids = append(ids, e.ID)
+			listed[e.ID] = true
+		}
+	}
+	for _, e := range entries {
+		if e.ParentID != 0 && e.ID != kdrive.RootID && !listed[e.ParentID] {
+			ids = append(ids, e.ParentID)
+			listed[e.ParentID] = true
+		}
+	}
+
+	accesses, err := client.FileAccesses(ctx, ids, func(done, total int) {
+		fmt.Fprintf(os.Stderr, "\r\033[KChecking access: %d/%d", done, total)
+	})
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		fmt.Fprintf(os.Stderr, "Warning: some accesses could not be read, results are partial: %v\n", err)
+	}
+	return accesses, nil
+}
+
+// principal is a user, team or invitation appearing in the access matrix
+type principal struct {
+	Kind  string `json:"kind"` // user, team or invitation
+	ID    int    `json:"id"`
+	Label string `json:"label"` // email or team name
+}
+
+func (p principal) key() string {
+	return p.Kind + ":" + strconv.Itoa(p.ID)
+}
+
+// column is the matrix header of a principal
+func (p principal) column() string {
+	switch p.Kind {
+	case shareTeam:
+		return "team:" + p.Label
+	case shareInvitation:
+		return "invite:" + p.Label
+	default:
+		return p.Label
+	}
+}
+
+// accessRow is the access of one folder in the matrix
+type accessRow struct {
+	ID        int               `json:"id"`
+	Path      string            `json:"path"`
+	Type      string            `json:"type"`
+	Rights    map[string]string `json:"rights"`    // principal column -> right
+	Diverges  bool              `json:"diverges"`  // differs from the parent
+	Changes   []string          `json:"changes"`   // differences with the parent
+	Unchecked bool              `json:"unchecked"` // access could not be read
+	rights    map[string]string // principal key -> right
+}
+
+// accessPrincipals returns the principals of an access with their rights
+func accessPrincipals(a *kdrive.FileAccess) (map[string]string, []principal) {
+	rights := make(map[string]string)
+	var principals []principal
+	add := func(p principal, right string) {
+		rights[p.key()] = right
+		principals = append(principals, p)
+	}
+	for _, u := range a.Users {
+		label := u.Email
+		if label == "" {
+			label = u.Name
+		}
+		add(principal{Kind: shareUser, ID: u.ID, Label: label}, u.Right)
+	}
+	for _, t := range a.Teams {
+		add(principal{Kind: shareTeam, ID: t.ID, Label: t.Name}, t.Right)
+	}
+	for _, inv := range a.Invitations {
+		add(principal{Kind: shareInvitation, ID: inv.ID, Label: inv.Email}, inv.Right)
+	}
+	return rights, principals
+}
+
+// accessMatrix builds one row per entry with the rights of every principal,
+// comparing each entry with its parent
+func accessMatrix(entries []*kdrive.File, accesses map[int]*kdrive.FileAccess) ([]accessRow, []principal) {
+	known := make(map[string]principal)
+	parentRights := func(id int) (map[string]string, bool) {
+		a, ok := accesses[id]
+		if !ok {
+			return nil, false
+		}
+		rights, _ := accessPrincipals(a)
+		return rights, true
+	}
+
+	rows := make([]accessRow, 0, len(entries))
+	for _, e := range entries {
+		row := accessRow{ID: e.ID, Path: e.Path, Type: e.Type, Rights: map[string]string{}}
+		a, ok := accesses[e.ID]
+		if !ok {
+			row.Unchecked = true
+			rows = append(rows, row)
+			continue
+		}
+		var principals []principal
+		row.rights, principals = accessPrincipals(a)
+		for _, p := range principals {
+			known[p.key()] = p
+			row.Rights[p.column()] = row.rights[p.key()]
+		}
+		rows = append(rows, row)
+	}
+
+	for i := range rows {
+		row := &rows[i]
+		if row.Unchecked || row.ID == kdrive.RootID {
+			continue
+		}
+		parent, ok := parentRights(entries[i].ParentID)
+		if !ok {
+			continue
+		}
+		row.Changes = accessChanges(parent, row.rights, known, accesses[entries[i].ParentID])
+		row.Diverges = len(row.Changes) > 0
+	}
+
+	principals := make([]principal, 0, len(known))
+	for _, p := range known {
+		principals = append(principals, p)
+	}
+	kindOrder := map[string]int{shareUser: 0, shareTeam: 1, shareInvitation: 2}
+	sort.Slice(principals, func(i, j int) bool {
+		if principals[i].Kind != principals[j].Kind {
+			return kindOrder[principals[i].Kind] < kindOrder[principals[j].Kind]
+		}
+		return principals[i].Label < principals[j].Label
+	})
+	sort.Slice(rows, func(i, j int) bool {
+		return rows[i].Path < rows[j].Path
+	})
+	return rows, principals
+}
+
+// accessChanges describes how rights differ from the parent ones:
+// +added (right), -removed, ~changed (old -> new)
+func accessChanges(parent, rights map[string]string, known map[string]principal, parentAccess *kdrive.FileAccess) []string {
+	// Principals only present on the parent are not in known yet
+	_, parentPrincipals := accessPrincipals(parentAccess)
+	labels := make(map[string]string)
+	for _, p := range parentPrincipals {
+		labels[p.key()] = p.column()
+	}
+	for k, p := range known {
+		labels[k] = p.column()
+	}
+
+	var changes []string
+	for k, right := range rights {
+		switch old, ok := parent[k]; {
+		case !ok:
+			changes = append(changes, fmt.Sprintf("+%s (%s)", labels[k], right))
+		case old != right:
+			changes = append(changes, fmt.Sprintf("~%s (%s -> %s)", labels[k], old, right))
+		}
+	}
+	for k := range parent {
+		if _, ok := rights[k]; !ok {
+			changes = append(changes, "-"+labels[k])
+		}
+	}
+	sort.Strings(changes)
+	return changes
+}
+
+// shortRight abbreviates a right for the table: R(ead), W(rite), M(anage)
+func shortRight(right string) string {
+	if right == "" {
+		return "-"
+	}
+	return strings.ToUpper(right[:1])
+}
+
+func printAccessTable(rows []accessRow, principals []principal) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	header := []string{"PATH"}
+	for _, p := range principals {
+		header = append(header, p.column())
+	}
+	header = append(header, "CHANGES FROM PARENT")
+	fmt.Fprintln(w, strings.Join(header, "\t"))
+
+	for _, r := range rows {
+		cells := []string{r.Path}
+		if r.Diverges {
+			cells[0] = "* " + r.Path
+		}
+		for _, p := range principals {
+			if r.Unchecked {
+				cells = append(cells, "?")
+			} else {
+				cells = append(cells, shortRight(r.Rights[p.column()]))
+			}
+		}
+		cells = append(cells, strings.Join(r.Changes, ", "))
+		fmt.Fprintln(w, strings.Join(cells, "\t"))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Println("\nR = read, W = write, M = manage, ? = access not readable, * = differs from the parent folder")
+	return nil
+}
+
+func printAccessCSV(rows []accessRow, principals []principal) error {
+	w := csv.NewWriter(os.Stdout)
+	header := []string{"path", "id"}
+	for _, p := range principals {
+		header = append(header, p.column())
+	}
+	header = append(header, "diverges", "changes")
+	w.Write(header)
+
+	for _, r := range rows {
+		record := []string{r.Path, strconv.Itoa(r.ID)}
+		for _, p := range principals {
+			record = append(record, r.Rights[p.column()])
+		}
+		record = append(record, strconv.FormatBool(r.Diverges), strings.Join(r.Changes, "; "))
+		w.Write(record)
+	}
+	w.Flush()
+	return w.Error()
+}
+
+func printAccessJSON(rows []accessRow, principals []principal) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(struct {
+		Principals []principal `json:"principals"`
+		Folders    []accessRow `json:"folders"`
+	}{principals, rows})
+}
+
+var accessCmd = &cobra.Command{
+	Use:   "access [path_or_id_or_glob...]",
+	Short: "Report who can access each folder",
+	Long: `Walk a subtree (default: root) and build a matrix of the rights of every user, team and
+invitation on each folder, flagging folders whose access differs from their parent (broken inheritance).`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		client := newClient()
+
+		var print func([]accessRow, []principal) error
+		switch accessFormat {
+		case "table":
+			print = printAccessTable
+		case "csv":
+			print = printAccessCSV
+		case "json":
+			print = printAccessJSON
+		default:
+			return fmt.Errorf("invalid format '%s' (table, csv, json)", accessFormat)
+		}
+
+		targets, err := resolveTargetArgs(ctx, client, args)
+		if err != nil {
+			return err
+		}
+
+		entries := targets
+		err = walkTargets(ctx, client, targets, nil, func(f *kdrive.File) {
+			if f.Type == "dir" {
+				entries = append(entries, f)
+			}
+		})
+		if err != nil {
+			return err
+		}
+		logging.Debug("reading folder access", "entries", len(entries))
+
+		accesses, err := fetchAccess(ctx, client, entries)
+		if err != nil {
+			return err
+		}
+
+		rows, principals := accessMatrix(entries, accesses)
+		if accessDiverging {
+			var kept []accessRow
+			for _, r := range rows {
+				if r.Diverges || r.Unchecked {
+					kept = append(kept, r)
+				}
+			}
+			rows = kept
+		}
+		if len(rows) == 0 {
+			fmt.Fprintln(os.Stderr, "No folder found")
+			return nil
+		}
+		return print(rows, principals)
+	},
+}
+
+func init() {
+	accessCmd.Flags().StringVar(&accessFormat, "format", "table", "Output format: table, csv, json")
+	accessCmd.Flags().BoolVar(&accessDiverging, "diverging", false, "Only folders whose access differs from their parent")
+	accessCmd.Flags().BoolVar(&strict, "strict", false, "Fail if any directory cannot be listed")
+	addWalkFlags(accessCmd)
+	addTargetFlags(accessCmd)
+	rootCmd.AddCommand(accessCmd)
+}
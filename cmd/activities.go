@@ -1,14 +1,16 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"iter"
 	"os"
 	"strings"
 	"text/tabwriter"
 	"time"
 
-	"github.com/gfaivre/ktools/internal/api"
 	"github.com/gfaivre/ktools/internal/logging"
+	"github.com/gfaivre/ktools/pkg/kdrive"
 	"github.com/spf13/cobra"
 )
 
@@ -20,7 +22,7 @@ var (
 	activitiesActions  []string
 	activitiesFrom     int64
 	activitiesUntil    int64
-	activitiesUsers    []int
+	activitiesUsers    []string
 )
 
 var activitiesCmd = &cobra.Command{
@@ -34,33 +36,25 @@ var activitiesCmd = &cobra.Command{
 		}
 
 		ctx := cmd.Context()
-		client := api.NewAdminClient(cfg)
+		client := newAdminClient()
+
+		users, err := resolveUserIDs(ctx, client, activitiesUsers)
+		if err != nil {
+			return err
+		}
 
 		order := "desc"
 		if activitiesAsc {
 			order = "asc"
 		}
 
-		opts := api.ActivitiesOptions{
+		opts := kdrive.ActivitiesOptions{
 			Limit:   activitiesLimit,
 			Order:   order,
 			Actions: activitiesActions,
 			From:    activitiesFrom,
 			Until:   activitiesUntil,
-			Users:   activitiesUsers,
-		}
-
-		var collected []api.Activity
-		for {
-			activities, nextCursor, hasMore, err := client.ListActivities(ctx, opts)
-			if err != nil {
-				return err
-			}
-			collected = append(collected, activities...)
-			if !hasMore || !activitiesAll {
-				break
-			}
-			opts.Cursor = nextCursor
+			Users:   users,
 		}
 
 		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
@@ -70,7 +64,18 @@ var activitiesCmd = &cobra.Command{
 			fmt.Fprintln(w, "DATE\tACTION\tUSER\tPATH\tID")
 		}
 
-		for _, a := range collected {
+		// Pages are fetched lazily with --all; otherwise only the first page is requested
+		activities := client.Activities(ctx, opts)
+		if !activitiesAll {
+			activities = firstActivities(ctx, client, opts)
+		}
+		total := 0
+		for a, err := range activities {
+			if err != nil {
+				return err
+			}
+			total++
+
 			t := time.Unix(a.CreatedAt, 0).Format("2006-01-02 15:04:05")
 
 			user := "-"
@@ -110,11 +115,27 @@ var activitiesCmd = &cobra.Command{
 		if err := w.Flush(); err != nil {
 			return err
 		}
-		fmt.Printf("\nTotal: %d activities\n", len(collected))
+		fmt.Printf("\nTotal: %d activities\n", total)
 		return nil
 	},
 }
 
+// firstActivities yields the first page of activities only
+func firstActivities(ctx context.Context, client *kdrive.Client, opts kdrive.ActivitiesOptions) iter.Seq2[kdrive.Activity, error] {
+	return func(yield func(kdrive.Activity, error) bool) {
+		page, _, _, err := client.ListActivities(ctx, opts)
+		if err != nil {
+			yield(kdrive.Activity{}, err)
+			return
+		}
+		for _, a := range page {
+			if !yield(a, nil) {
+				return
+			}
+		}
+	}
+}
+
 func init() {
 	activitiesCmd.Flags().IntVarP(&activitiesLimit, "limit", "n", 50, "Number of activities per page (max 1000)")
 	activitiesCmd.Flags().BoolVarP(&activitiesAll, "all", "a", false, "Fetch all pages")
@@ -123,6 +144,6 @@ func init() {
 	activitiesCmd.Flags().StringArrayVar(&activitiesActions, "action", nil, "Filter by action (repeatable, e.g. --action file_trash --action file_delete)")
 	activitiesCmd.Flags().Int64Var(&activitiesFrom, "from", 0, "Filter from timestamp (Unix)")
 	activitiesCmd.Flags().Int64Var(&activitiesUntil, "until", 0, "Filter until timestamp (Unix)")
-	activitiesCmd.Flags().IntSliceVar(&activitiesUsers, "user", nil, "Filter by user ID (repeatable)")
+	activitiesCmd.Flags().StringArrayVar(&activitiesUsers, "user", nil, "Filter by user ID, email or name (repeatable)")
 	rootCmd.AddCommand(activitiesCmd)
 }
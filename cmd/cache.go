@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/gfaivre/ktools/internal/directory"
+	"github.com/gfaivre/ktools/internal/pathcache"
+	"github.com/spf13/cobra"
+)
+
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Manage the local path and user caches",
+}
+
+var cacheClearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "Forget the cached paths and users of the drive",
+	Long: `Delete the persistent path -> ID cache and the users and teams directory of the configured drive.
+Paths are resolved again by listing directories, users are fetched again when needed.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := pathcache.Clear(cfg.DriveID); err != nil {
+			return fmt.Errorf("cannot clear path cache: %w", err)
+		}
+		if err := directory.Clear(cfg.DriveID); err != nil {
+			return fmt.Errorf("cannot clear user directory: %w", err)
+		}
+		fmt.Println("Path and user caches cleared")
+		return nil
+	},
+}
+
+func init() {
+	cacheCmd.AddCommand(cacheClearCmd)
+	rootCmd.AddCommand(cacheCmd)
+}
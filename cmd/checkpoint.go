@@ -0,0 +1,254 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/gfaivre/ktools/internal/checkpoint"
+	"github.com/gfaivre/ktools/internal/logging"
+	"github.com/gfaivre/ktools/pkg/kdrive"
+	"github.com/spf13/cobra"
+)
+
+var (
+	resume       bool
+	strict       bool
+	walkMaxDepth int
+	walkExclude  []string
+)
+
+// checkpointCommand returns the checkpoint key of a command run: its command
+// line without --resume, followed by the targets read from stdin or --from-file
+func checkpointCommand(cmd *cobra.Command, args []string) string {
+	parts := append([]string{commandLine(cmd, args, "resume")}, readTargetLines...)
+	return strings.Join(parts, " ")
+}
+
+// openCheckpoint opens the checkpoint of the running command, loading it when --resume is set
+func openCheckpoint(cmd *cobra.Command, args []string) (*checkpoint.Checkpoint, error) {
+	cp, err := checkpoint.Open(cfg.DriveID, checkpointCommand(cmd, args))
+	if err != nil {
+		return nil, err
+	}
+	if !resume {
+		return cp, nil
+	}
+
+	found, err := cp.Load()
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		fmt.Fprintln(os.Stderr, "No checkpoint found, starting from scratch")
+		return cp, nil
+	}
+
+	listed, pending := 0, 0
+	if cp.State.Walk != nil {
+		listed = len(cp.State.Walk.Files)
+		pending = len(cp.State.Walk.Pending) + len(cp.State.Walk.Failed)
+	}
+	if cp.State.Aggregate != nil {
+		fmt.Fprintf(os.Stderr, "Resuming from checkpoint of %s (%d directories left to list)\n",
+			cp.State.UpdatedAt.Format("2006-01-02 15:04:05"), pending)
+		return cp, nil
+	}
+	fmt.Fprintf(os.Stderr, "Resuming from checkpoint of %s (%d entries listed, %d processed)\n",
+		cp.State.UpdatedAt.Format("2006-01-02 15:04:05"), listed, len(cp.State.Done))
+	return cp, nil
+}
+
+// saveCheckpoint persists a checkpoint after an interrupted run
+func saveCheckpoint(cp *checkpoint.Checkpoint) {
+	if err := cp.Save(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not save checkpoint: %v\n", err)
+		return
+	}
+	fmt.Fprintln(os.Stderr, "Progress saved, rerun with --resume to continue")
+}
+
+// walkTree lists the trees below roots recursively, resuming from and checkpointing to cp.
+// Unless --strict is set, directories that cannot be listed are reported on
+// stderr and the partial listing is returned; they stay in the checkpoint so
+// --resume retries them.
+func walkTree(ctx context.Context, client *kdrive.Client, cp *checkpoint.Checkpoint, roots []kdrive.WalkDir) ([]kdrive.File, error) {
+	if cp.State.WalkDone && cp.State.Walk != nil && len(cp.State.Walk.Failed) == 0 {
+		return cp.State.Walk.Files, nil
+	}
+	if len(roots) == 0 {
+		return nil, nil
+	}
+
+	saved := false
+	opts := kdrive.WalkOptions{
+		Strict: strict,
+		State:  walkState(cp, roots),
+		Checkpoint: func(s *kdrive.WalkState) {
+			cp.State.Walk = s
+			if err := cp.Save(); err != nil {
+				logging.Debug("checkpoint save failed", "err", err)
+			}
+			saved = true
+		},
+	}
+
+	files, err := client.ListFilesRecursiveWithOptions(ctx, roots[0].ID, roots[0].Name, scanProgress, opts)
+	fmt.Fprintln(os.Stderr)
+
+	var partial *kdrive.PartialError
+	if errors.As(err, &partial) {
+		printPartialError(partial)
+		fmt.Fprintln(os.Stderr, "Rerun with --resume to retry them, or --strict to fail instead")
+		fmt.Fprintln(os.Stderr)
+		cp.State.WalkDone = true
+		return files, nil
+	}
+	if err != nil {
+		if saved {
+			fmt.Fprintln(os.Stderr, "Progress saved, rerun with --resume to continue")
+		}
+		return nil, err
+	}
+
+	cp.State.Walk = &kdrive.WalkState{Files: files}
+	cp.State.WalkDone = true
+	return files, nil
+}
+
+// addWalkFlags registers the flags limiting a streaming walk
+func addWalkFlags(cmd *cobra.Command) {
+	cmd.Flags().IntVar(&walkMaxDepth, "max-depth", 0, "Only descend N levels below the start directory (0 = unlimited)")
+	cmd.Flags().StringArrayVar(&walkExclude, "exclude", nil, "Skip subtrees whose directory name matches a glob, or with this ID (repeatable)")
+}
+
+// walkOptions builds the walk options from the --max-depth and --exclude flags
+func walkOptions() kdrive.WalkOptions {
+	opts := kdrive.WalkOptions{MaxDepth: walkMaxDepth}
+	if len(walkExclude) == 0 {
+		return opts
+	}
+	opts.Prune = func(dir *kdrive.File) bool {
+		for _, pattern := range walkExclude {
+			if id, err := strconv.Atoi(pattern); err == nil && id == dir.ID {
+				return true
+			}
+			if ok, _ := path.Match(pattern, dir.Name); ok {
+				return true
+			}
+		}
+		return false
+	}
+	return opts
+}
+
+// walkState returns the walk state to resume from cp, or a fresh one with the
+// roots pending so several trees are listed by a single walk
+func walkState(cp *checkpoint.Checkpoint, roots []kdrive.WalkDir) *kdrive.WalkState {
+	if cp.State.Walk != nil {
+		return cp.State.Walk
+	}
+	return &kdrive.WalkState{Pending: roots}
+}
+
+// streamTree calls add for every target that is not a directory, then walks the
+// trees below the directory targets calling add for every file, without keeping the
+// listing in memory. agg holds what the command aggregates from the files: it
+// is saved with every checkpoint and restored on --resume, so it must be
+// JSON-serializable. Unlistable directories are handled as in walkTree.
+func streamTree(ctx context.Context, client *kdrive.Client, cp *checkpoint.Checkpoint, targets []*kdrive.File, opts kdrive.WalkOptions, agg any, add func(*kdrive.File)) error {
+	if cp.State.Aggregate != nil {
+		if err := json.Unmarshal(cp.State.Aggregate, agg); err != nil {
+			return fmt.Errorf("invalid checkpoint: %w", err)
+		}
+		if cp.State.WalkDone && cp.State.Walk != nil && len(cp.State.Walk.Failed) == 0 {
+			return nil
+		}
+	} else {
+		for _, t := range targets {
+			if t.Type != "dir" {
+				add(t)
+			}
+		}
+	}
+
+	roots := walkRoots(targets)
+	if len(roots) == 0 {
+		cp.State.Walk = &kdrive.WalkState{}
+		cp.State.WalkDone = true
+		return nil
+	}
+
+	saved := false
+	opts.Strict = strict
+	opts.State = walkState(cp, roots)
+	opts.Checkpoint = func(s *kdrive.WalkState) {
+		data, err := json.Marshal(agg)
+		if err != nil {
+			logging.Debug("checkpoint encoding failed", "err", err)
+			return
+		}
+		cp.State.Walk = s
+		cp.State.Aggregate = data
+		if err := cp.Save(); err != nil {
+			logging.Debug("checkpoint save failed", "err", err)
+		}
+		saved = true
+	}
+
+	err := client.Walk(ctx, roots[0].ID, roots[0].Name, scanProgress, opts, func(f *kdrive.File) error {
+		add(f)
+		return nil
+	})
+	fmt.Fprintln(os.Stderr)
+
+	var partial *kdrive.PartialError
+	if errors.As(err, &partial) {
+		printPartialError(partial)
+		fmt.Fprintln(os.Stderr, "Rerun with --resume to retry them, or --strict to fail instead")
+		fmt.Fprintln(os.Stderr)
+		cp.State.WalkDone = true
+		return nil
+	}
+	if err != nil {
+		if saved {
+			fmt.Fprintln(os.Stderr, "Progress saved, rerun with --resume to continue")
+		}
+		return err
+	}
+
+	cp.State.Walk = &kdrive.WalkState{}
+	cp.State.WalkDone = true
+	return nil
+}
+
+// printPartialError summarizes the directories a tolerant walk had to skip
+func printPartialError(partial *kdrive.PartialError) {
+	fmt.Fprintf(os.Stderr, "Warning: %d directories could not be listed, results are partial:\n", len(partial.Dirs))
+	w := tabwriter.NewWriter(os.Stderr, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "  ID\tNAME\tERROR")
+	for _, d := range partial.Dirs {
+		fmt.Fprintf(w, "  %d\t%s\t%v\n", d.ID, d.Name, d.Err)
+	}
+	w.Flush()
+}
+
+// finishCheckpoint removes the checkpoint of a completed run, unless directories
+// failed to list and should be retried with --resume
+func finishCheckpoint(cp *checkpoint.Checkpoint) {
+	if cp.State.Walk != nil && len(cp.State.Walk.Failed) > 0 {
+		if err := cp.Save(); err != nil {
+			logging.Debug("checkpoint save failed", "err", err)
+		}
+		return
+	}
+	if err := cp.Remove(); err != nil {
+		logging.Debug("checkpoint cleanup failed", "err", err)
+	}
+}
@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"log/slog"
+	"net/http"
+	"os"
+	"sync"
+
+	"github.com/gfaivre/ktools/internal/logging"
+	"github.com/gfaivre/ktools/internal/pathcache"
+	"github.com/gfaivre/ktools/pkg/kdrive"
+)
+
+var (
+	clientOptsOnce sync.Once
+	clientOpts     []kdrive.Option
+	pathIndex      *kdrive.PathIndex // shared by every client, persisted when path_cache is set
+	transport      http.RoundTripper // built from the configuration before any command runs
+)
+
+// newClient builds a kDrive client from the configuration
+func newClient() *kdrive.Client {
+	return kdrive.New(cfg.APIToken, cfg.DriveID, clientOptions()...)
+}
+
+// newAdminClient builds a client using the admin token (for audit/activity endpoints).
+// Falls back to the standard token if admin_token is not configured.
+func newAdminClient() *kdrive.Client {
+	token := cfg.AdminToken
+	if token == "" {
+		token = cfg.APIToken
+	}
+	return kdrive.New(token, cfg.DriveID, clientOptions()...)
+}
+
+// clientOptions translates the configuration into client options. The rate
+// limiter and transport are shared by every client of the process, so normal
+// and admin clients draw from the same budget and recordings form one sequence.
+func clientOptions() []kdrive.Option {
+	clientOptsOnce.Do(func() {
+		limiter := kdrive.NewRateLimiter(cfg.RateLimit, cfg.RateBurst, cfg.MaxRateLimit, cfg.AdaptiveRate)
+		if cfg.ReplayDir != "" {
+			limiter = kdrive.Unlimited() // no network, no need to pace
+		}
+
+		pathIndex = loadPathIndex()
+
+		clientOpts = []kdrive.Option{
+			kdrive.WithBaseURL(cfg.BaseURL),
+			kdrive.WithUserAgent(cfg.UserAgent),
+			kdrive.WithRateLimiter(limiter),
+			kdrive.WithRetry(cfg.MaxAttempts, cfg.RetryBudget),
+			kdrive.WithWorkers(cfg.Workers),
+			kdrive.WithTransport(transport),
+			kdrive.WithPathIndex(pathIndex),
+			kdrive.WithCaseSensitivePaths(cfg.CaseSensitivePaths),
+			kdrive.WithLogger(slog.Default()),
+		}
+	})
+	return clientOpts
+}
+
+// loadPathIndex returns the persisted path index of the drive, or an empty one
+// when the cache is disabled, unreadable or in replay mode
+func loadPathIndex() *kdrive.PathIndex {
+	if !cfg.PathCache || cfg.ReplayDir != "" {
+		return kdrive.NewPathIndex()
+	}
+	x, err := pathcache.Load(cfg.DriveID, cfg.PathCacheTTL)
+	if err != nil {
+		logging.Debug("path cache ignored", "err", err)
+	}
+	logging.Debug("path cache loaded", "entries", x.Len())
+	return x
+}
+
+// savePathIndex persists the path index if it was used and changed
+func savePathIndex() {
+	if pathIndex == nil || !cfg.PathCache || cfg.ReplayDir != "" {
+		return
+	}
+	if err := pathcache.Save(cfg.DriveID, pathIndex); err != nil {
+		logging.Debug("path cache not saved", "err", err)
+	}
+}
+
+// newTransport builds the HTTP transport chain from the configuration:
+// trace -> record -> network (or replay).
+func newTransport() (http.RoundTripper, error) {
+	var rt http.RoundTripper
+	var err error
+
+	if cfg.ReplayDir != "" {
+		rt, err = kdrive.NewReplayTransport(cfg.ReplayDir)
+	} else {
+		if cfg.InsecureTLS {
+			logging.Debug("TLS certificate verification disabled")
+		}
+		rt, err = kdrive.NewTransport(kdrive.TransportOptions{
+			ProxyURL:           cfg.ProxyURL,
+			CAFile:             cfg.CAFile,
+			ClientCert:         cfg.ClientCert,
+			ClientKey:          cfg.ClientKey,
+			InsecureSkipVerify: cfg.InsecureTLS,
+			MaxIdleConns:       cfg.MaxIdleConns,
+			MaxConnsPerHost:    cfg.MaxConnsPerHost,
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.RecordDir != "" {
+		if rt, err = kdrive.NewRecordTransport(rt, cfg.RecordDir); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.Trace {
+		rt = kdrive.NewTraceTransport(rt, os.Stderr)
+	}
+	return rt, nil
+}
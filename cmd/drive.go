@@ -0,0 +1,168 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/gfaivre/ktools/internal/logging"
+	"github.com/gfaivre/ktools/internal/usage"
+	"github.com/spf13/cobra"
+)
+
+var (
+	driveTop      int
+	drivePeriod   string
+	driveNoRecord bool
+)
+
+var driveCmd = &cobra.Command{
+	Use:   "drive",
+	Short: "Show drive information",
+}
+
+var driveInfoCmd = &cobra.Command{
+	Use:   "info",
+	Short: "Show quota, usage and growth of the drive",
+	Long: `Show the plan and quota of the drive, the space used by the trash, file versions and each user,
+and the growth over a period (default: 30 days). Each run records a usage sample in ~/.config/ktools/usage:
+growth and the projected date the quota will be full are computed from this history.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		client := newAdminClient()
+
+		days, err := parseAge(drivePeriod)
+		if err != nil {
+			return fmt.Errorf("invalid period: %w", err)
+		}
+
+		drive, err := client.GetDrive(ctx)
+		if err != nil {
+			return err
+		}
+		sizes, err := client.GetDriveSizes(ctx)
+		if err != nil {
+			return err
+		}
+		users, err := client.UsersUsage(ctx)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		sample := usage.Sample{
+			Time:     now,
+			Quota:    drive.Size,
+			Used:     drive.UsedSize,
+			Trash:    sizes.Trash,
+			Versions: sizes.Versions,
+			Users:    make(map[int]int64, len(users)),
+		}
+		for _, u := range users {
+			sample.Users[u.UserID] = u.UsedSize
+		}
+
+		history, err := usage.Load(cfg.DriveID)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+		if !driveNoRecord && cfg.ReplayDir == "" {
+			if err := usage.Append(cfg.DriveID, sample); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: usage sample not recorded: %v\n", err)
+			}
+		}
+		logging.Debug("usage history", "samples", len(history))
+
+		since := now.AddDate(0, 0, -days)
+		base := usage.Baseline(history, since)
+		history = append(history, sample)
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(w, "Drive:\t%s (%d)\n", drive.Name, drive.ID)
+		fmt.Fprintf(w, "Plan:\t%s\n", drive.Pack.Name)
+		if drive.Size > 0 {
+			fmt.Fprintf(w, "Quota:\t%s used of %s (%.1f%%)\n",
+				formatSize(drive.UsedSize), formatSize(drive.Size), float64(drive.UsedSize)*100/float64(drive.Size))
+			fmt.Fprintf(w, "Free:\t%s\n", formatSize(max(drive.Size-drive.UsedSize, 0)))
+		} else {
+			fmt.Fprintf(w, "Quota:\t%s used (unlimited)\n", formatSize(drive.UsedSize))
+		}
+		fmt.Fprintf(w, "Trash:\t%s\n", formatSize(sizes.Trash))
+		fmt.Fprintf(w, "Versions:\t%s\n", formatSize(sizes.Versions))
+
+		fmt.Fprintln(w)
+		if base == nil {
+			fmt.Fprintf(w, "Growth:\tno history yet (recorded at each run)\n")
+		} else {
+			fmt.Fprintf(w, "Growth:\t%s since %s\n", formatDelta(drive.UsedSize-base.Used), base.Time.Format("2006-01-02"))
+		}
+		if perDay, ok := usage.DailyGrowth(history, since); !ok {
+			fmt.Fprintf(w, "Trend:\tnot enough history over %s\n", drivePeriod)
+		} else {
+			fmt.Fprintf(w, "Trend:\t%s/day over %s\n", formatDelta(int64(perDay)), drivePeriod)
+			if at, ok := usage.Exhaustion(sample, perDay); ok {
+				fmt.Fprintf(w, "Quota full:\t%s (in %d days)\n", at.Format("2006-01-02"), int(at.Sub(now).Hours()/24))
+			} else if drive.Size > 0 {
+				fmt.Fprintf(w, "Quota full:\tnot projected (usage not growing)\n")
+			}
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+
+		if len(users) == 0 {
+			return nil
+		}
+		sort.Slice(users, func(i, j int) bool {
+			return users[i].UsedSize > users[j].UsedSize
+		})
+		shown := users
+		if driveTop > 0 && len(shown) > driveTop {
+			shown = shown[:driveTop]
+		}
+		names := userDirectory(ctx, client)
+
+		fmt.Println()
+		w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "USER\tUSED\t%\tGROWTH")
+		for _, u := range shown {
+			pct := 0.0
+			if drive.UsedSize > 0 {
+				pct = float64(u.UsedSize) * 100 / float64(drive.UsedSize)
+			}
+			growth := "-"
+			if base != nil {
+				if before, ok := base.Users[u.UserID]; ok {
+					growth = formatDelta(u.UsedSize - before)
+				}
+			}
+			fmt.Fprintf(w, "%s\t%s\t%.1f%%\t%s\n", names.UserName(u.UserID), formatSize(u.UsedSize), pct, growth)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		if len(users) > len(shown) {
+			fmt.Printf("\n... and %d more users\n", len(users)-len(shown))
+		}
+		return nil
+	},
+}
+
+// formatDelta formats a size change with its sign
+func formatDelta(bytes int64) string {
+	if bytes < 0 {
+		return "-" + formatSize(-bytes)
+	}
+	return "+" + formatSize(bytes)
+}
+
+func init() {
+	driveInfoCmd.Flags().IntVarP(&driveTop, "top", "n", 10, "Show the top N users by usage (0 = all)")
+	driveInfoCmd.Flags().StringVar(&drivePeriod, "period", "30d", "Period for growth and trend (e.g., 30d, 6m, 1y)")
+	driveInfoCmd.Flags().BoolVar(&driveNoRecord, "no-record", false, "Do not add this run to the usage history")
+	driveCmd.AddCommand(driveInfoCmd)
+	rootCmd.AddCommand(driveCmd)
+}
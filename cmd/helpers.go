@@ -3,12 +3,51 @@ package cmd
 import (
 	"context"
 	"fmt"
+	"os"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
 
-	"github.com/gfaivre/ktools/internal/api"
+	"github.com/gfaivre/ktools/pkg/kdrive"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
+// isSecretFlag reports whether a flag value must not be persisted (passwords, tokens)
+func isSecretFlag(name string) bool {
+	name = strings.ToLower(name)
+	return strings.Contains(name, "password") || strings.Contains(name, "token") || strings.Contains(name, "secret")
+}
+
+// commandLine rebuilds the command line of a run from its parsed flags (in name
+// order, whatever their syntax, secret values masked) and arguments. Flags in
+// skip are left out. Used for the journal and checkpoint keys, never os.Args.
+func commandLine(cmd *cobra.Command, args []string, skip ...string) string {
+	parts := []string{cmd.CommandPath()}
+	cmd.LocalFlags().VisitAll(func(f *pflag.Flag) {
+		if !f.Changed || slices.Contains(skip, f.Name) {
+			return
+		}
+		switch value := f.Value.String(); {
+		case isSecretFlag(f.Name):
+			parts = append(parts, "--"+f.Name+"=***")
+		case f.Value.Type() == "bool" && value == "true":
+			parts = append(parts, "--"+f.Name)
+		default:
+			parts = append(parts, "--"+f.Name+"="+value)
+		}
+	})
+	return strings.Join(append(parts, args...), " ")
+}
+
+// stderrIsTerminal reports whether stderr is a terminal, to only draw
+// carriage-return progress lines there
+func stderrIsTerminal() bool {
+	fi, err := os.Stderr.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
 // truncateName truncates a string to max length with ellipsis
 func truncateName(name string, max int) string {
 	if len(name) <= max {
@@ -23,9 +62,12 @@ func formatSize(bytes int64) string {
 		KB = 1024
 		MB = KB * 1024
 		GB = MB * 1024
+		TB = GB * 1024
 	)
 
 	switch {
+	case bytes >= TB:
+		return fmt.Sprintf("%.1f TB", float64(bytes)/TB)
 	case bytes >= GB:
 		return fmt.Sprintf("%.1f GB", float64(bytes)/GB)
 	case bytes >= MB:
@@ -55,8 +97,48 @@ func hexToANSI(hex string) string {
 	return fmt.Sprintf("\033[48;2;%d;%d;%dm  \033[0m", r, g, b)
 }
 
-// resolveFileID resolves a file ID or path to an ID
-func resolveFileID(ctx context.Context, client *api.Client, idOrPath string) (int, error) {
+// expandBookmark expands a "~name" or "~name/rest" argument using the
+// bookmarks of the configuration. Other arguments are returned unchanged.
+func expandBookmark(ctx context.Context, client *kdrive.Client, arg string) (string, error) {
+	if !strings.HasPrefix(arg, "~") {
+		return arg, nil
+	}
+	name, rest, _ := strings.Cut(arg[1:], "/")
+	target, ok := cfg.Bookmarks[name]
+	if !ok {
+		names := make([]string, 0, len(cfg.Bookmarks))
+		for n := range cfg.Bookmarks {
+			names = append(names, "~"+n)
+		}
+		sort.Strings(names)
+		if len(names) == 0 {
+			return "", fmt.Errorf("unknown bookmark '~%s' (no bookmarks configured)", name)
+		}
+		return "", fmt.Errorf("unknown bookmark '~%s' (known: %s)", name, strings.Join(names, ", "))
+	}
+
+	if id, err := strconv.Atoi(target); err == nil {
+		if rest == "" {
+			return target, nil
+		}
+		dir, err := client.FilePath(ctx, id)
+		if err != nil {
+			return "", fmt.Errorf("bookmark '~%s': %w", name, err)
+		}
+		target = dir
+	}
+	if rest == "" {
+		return target, nil
+	}
+	return kdrive.JoinPath(target, rest), nil
+}
+
+// resolveFileID resolves a file ID, path or bookmark to an ID
+func resolveFileID(ctx context.Context, client *kdrive.Client, idOrPath string) (int, error) {
+	idOrPath, err := expandBookmark(ctx, client, idOrPath)
+	if err != nil {
+		return 0, err
+	}
 	if id, err := strconv.Atoi(idOrPath); err == nil {
 		return id, nil
 	}
@@ -69,11 +151,16 @@ func resolveFileID(ctx context.Context, client *api.Client, idOrPath string) (in
 
 // resolveStartPath resolves a path or ID argument to startID and startName
 // Returns (1, "/") if no argument provided
-func resolveStartPath(ctx context.Context, client *api.Client, arg string) (int, string, error) {
+func resolveStartPath(ctx context.Context, client *kdrive.Client, arg string) (int, string, error) {
 	if arg == "" {
 		return 1, "/", nil
 	}
 
+	arg, err := expandBookmark(ctx, client, arg)
+	if err != nil {
+		return 0, "", err
+	}
+
 	if id, err := strconv.Atoi(arg); err == nil {
 		file, err := client.GetFile(ctx, id)
 		if err != nil {
@@ -88,3 +175,30 @@ func resolveStartPath(ctx context.Context, client *api.Client, arg string) (int,
 	}
 	return file.ID, file.Name, nil
 }
+
+// parseSize parses a size like "1048576", "500K", "10MB" or "2G" into bytes
+func parseSize(size string) (int64, error) {
+	s := strings.ToUpper(strings.TrimSpace(size))
+	s = strings.TrimSuffix(s, "B")
+
+	multiplier := int64(1)
+	switch {
+	case strings.HasSuffix(s, "K"):
+		multiplier = 1024
+	case strings.HasSuffix(s, "M"):
+		multiplier = 1024 * 1024
+	case strings.HasSuffix(s, "G"):
+		multiplier = 1024 * 1024 * 1024
+	case strings.HasSuffix(s, "T"):
+		multiplier = 1024 * 1024 * 1024 * 1024
+	}
+	if multiplier > 1 {
+		s = s[:len(s)-1]
+	}
+
+	value, err := strconv.ParseFloat(s, 64)
+	if err != nil || value < 0 {
+		return 0, fmt.Errorf("invalid size '%s' (ex: 1048576, 500K, 10MB, 2G)", size)
+	}
+	return int64(value * float64(multiplier)), nil
+}
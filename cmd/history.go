@@ -0,0 +1,248 @@
+package cmd
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/gfaivre/ktools/internal/journal"
+	"github.com/gfaivre/ktools/pkg/kdrive"
+	"github.com/spf13/cobra"
+)
+
+var (
+	historyLimit int
+	historyIDs   bool
+	undoDryRun   bool
+)
+
+// newJournalEntry starts a journal entry for the running command
+func newJournalEntry(cmd *cobra.Command, args []string) *journal.Entry {
+	return journal.New(cfg.DriveID, commandLine(cmd, args))
+}
+
+// saveJournal persists a journal entry, warning (not failing) on error
+func saveJournal(entry *journal.Entry) {
+	if err := journal.Save(entry); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not write journal: %v\n", err)
+		return
+	}
+	switch {
+	case entry.Changed() == 0:
+	case entry.Undoable():
+		fmt.Fprintf(os.Stderr, "Journal: %s (revert with 'ktools undo %s')\n", entry.ID, entry.ID)
+	default:
+		fmt.Fprintf(os.Stderr, "Journal: %s\n", entry.ID)
+	}
+}
+
+func entryStatus(e *journal.Entry) string {
+	switch {
+	case e.UndoneBy != "":
+		return "undone by " + e.UndoneBy
+	case e.UndoOf != "":
+		return "undo of " + e.UndoOf
+	case !e.Undoable():
+		return "not undoable"
+	default:
+		return "-"
+	}
+}
+
+// loadJournalEntry reads a journal entry of the configured drive
+func loadJournalEntry(id string) (*journal.Entry, error) {
+	e, err := journal.Load(id)
+	if err != nil {
+		return nil, err
+	}
+	if e.DriveID != cfg.DriveID {
+		return nil, fmt.Errorf("journal entry %s belongs to drive %d (current: %d)", e.ID, e.DriveID, cfg.DriveID)
+	}
+	return e, nil
+}
+
+// listJournal returns the journal entries of the configured drive, warning
+// about the unreadable ones instead of failing
+func listJournal() []*journal.Entry {
+	entries, err := journal.List(cfg.DriveID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+	return entries
+}
+
+var historyCmd = &cobra.Command{
+	Use:   "history [journal_id]",
+	Short: "Show journaled mutations",
+	Long: `List journaled mutating operations (most recent first), or show the details of one entry.
+
+Category assignments (tag add, tag rm, tag apply, undo) are journaled with the files they changed
+and can be reverted with 'ktools undo'. Category, share link, share and version changes (tag
+create/edit/delete/import, link create/update/delete, shares revoke, versions restore/prune) are
+journaled for the record only: they cannot be undone.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 1 {
+			e, err := loadJournalEntry(args[0])
+			if err != nil {
+				return err
+			}
+			printJournalEntry(e)
+			return nil
+		}
+
+		entries := listJournal()
+		if len(entries) == 0 {
+			fmt.Println("No journal entries")
+			return nil
+		}
+
+		displayed := entries
+		if historyLimit > 0 && len(displayed) > historyLimit {
+			displayed = displayed[:historyLimit]
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tDATE\tCHANGED\tSTATUS\tCOMMAND")
+		for _, e := range displayed {
+			fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\n",
+				e.ID, e.CreatedAt.Format("2006-01-02 15:04:05"), e.Changed(), entryStatus(e), e.Command)
+		}
+		return w.Flush()
+	},
+}
+
+func printJournalEntry(e *journal.Entry) {
+	fmt.Printf("ID:       %s\n", e.ID)
+	fmt.Printf("Date:     %s\n", e.CreatedAt.Format("2006-01-02 15:04:05"))
+	fmt.Printf("Drive:    %d\n", e.DriveID)
+	fmt.Printf("Command:  %s\n", e.Command)
+	fmt.Printf("Status:   %s\n", entryStatus(e))
+	fmt.Println()
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "OPERATION\tCATEGORY\tFILES\tDETAIL")
+	for _, op := range e.Operations {
+		category := "-"
+		if op.CategoryID > 0 {
+			category = fmt.Sprintf("%s (%d)", op.CategoryName, op.CategoryID)
+		}
+		detail := op.Detail
+		if detail == "" {
+			detail = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\n", op.Kind, category, len(op.FileIDs), detail)
+	}
+	w.Flush()
+
+	if historyIDs {
+		for _, op := range e.Operations {
+			ids := make([]string, len(op.FileIDs))
+			for i, id := range op.FileIDs {
+				ids[i] = strconv.Itoa(id)
+			}
+			label := strings.TrimSpace(op.Kind + " " + cmp.Or(op.CategoryName, op.Detail))
+			fmt.Printf("\n%s: %s\n", label, strings.Join(ids, ","))
+		}
+	}
+}
+
+var undoCmd = &cobra.Command{
+	Use:   "undo [journal_id]",
+	Short: "Revert a journaled mutation",
+	Long:  "Revert the changes recorded in a journal entry (default: the most recent one not yet undone). Only files whose state was actually changed are reverted.",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+
+		var target *journal.Entry
+		if len(args) == 1 {
+			e, err := loadJournalEntry(args[0])
+			if err != nil {
+				return err
+			}
+			target = e
+		} else {
+			for _, e := range listJournal() {
+				if e.UndoneBy == "" && e.UndoOf == "" && e.Undoable() {
+					target = e
+					break
+				}
+			}
+			if target == nil {
+				return fmt.Errorf("nothing to undo")
+			}
+		}
+
+		if target.UndoneBy != "" {
+			return fmt.Errorf("journal entry %s already undone by %s", target.ID, target.UndoneBy)
+		}
+		if !target.Undoable() {
+			return fmt.Errorf("journal entry %s cannot be undone (only category assignments can)", target.ID)
+		}
+
+		fmt.Fprintf(os.Stderr, "Reverting %s: %s\n", target.ID, target.Command)
+		if undoDryRun {
+			for i := len(target.Operations) - 1; i >= 0; i-- {
+				op := target.Operations[i]
+				fmt.Printf("%s\t%s (%d)\t%d files\n", op.Inverse().Kind, op.CategoryName, op.CategoryID, len(op.FileIDs))
+			}
+			return nil
+		}
+
+		client := newClient()
+		undo := newJournalEntry(cmd, args)
+		undo.UndoOf = target.ID
+
+		err := revertEntry(ctx, client, target, undo)
+
+		// Record what was reverted, even partially
+		saveJournal(undo)
+		if err != nil {
+			return err
+		}
+
+		target.UndoneBy = undo.ID
+		if err := journal.Save(target); err != nil {
+			return err
+		}
+
+		fmt.Fprintf(os.Stderr, "Done: %d changes reverted\n", undo.Changed())
+		return nil
+	},
+}
+
+// revertEntry applies the inverse of every operation of e (last first), recording changes in undo
+func revertEntry(ctx context.Context, client *kdrive.Client, e *journal.Entry, undo *journal.Entry) error {
+	var failed int
+	for i := len(e.Operations) - 1; i >= 0; i-- {
+		op := e.Operations[i]
+		inv := op.Inverse()
+
+		res, err := updateCategory(ctx, client, inv.Kind, op.CategoryID, op.FileIDs, fmt.Sprintf("%s [%s]", inv.Kind, op.CategoryName), nil, nil)
+		if res != nil {
+			undo.Record(inv.Kind, op.CategoryID, op.CategoryName, res.Changed)
+			printBatchFailures(res.Failed, nil)
+			failed += len(res.Failed)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d files could not be reverted", failed)
+	}
+	return nil
+}
+
+func init() {
+	historyCmd.Flags().IntVarP(&historyLimit, "limit", "n", 20, "Show the N most recent entries (0 = unlimited)")
+	historyCmd.Flags().BoolVar(&historyIDs, "ids", false, "Print changed file IDs when showing an entry")
+	undoCmd.Flags().BoolVar(&undoDryRun, "dry-run", false, "Show what would be reverted without applying it")
+	rootCmd.AddCommand(historyCmd)
+	rootCmd.AddCommand(undoCmd)
+}
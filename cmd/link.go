@@ -0,0 +1,457 @@
+package cmd
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/gfaivre/ktools/internal/journal"
+	"github.com/gfaivre/ktools/pkg/kdrive"
+	"github.com/spf13/cobra"
+)
+
+var (
+	linkPassword string
+	linkExpires  string
+	linkRight    string
+	linkDownload bool
+	linkEdit     bool
+	linkCSV      string
+	linkDryRun   bool
+)
+
+// linkResult is the outcome of a share link operation on one entry
+type linkResult struct {
+	ID     int
+	Type   string
+	Path   string
+	Link   *kdrive.ShareLink
+	Status string // created, exists, updated, deleted or error: ...
+}
+
+// parseExpiry parses an expiry date (2025-12-31), a delay from now (30d, 6m, 1y)
+// or "never". The returned time is 0 for "" and "never".
+func parseExpiry(s string, now time.Time) (int64, bool, error) {
+	switch s {
+	case "":
+		return 0, false, nil
+	case "never":
+		return 0, true, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", s, time.Local); err == nil {
+		return t.Add(24*time.Hour - time.Second).Unix(), false, nil // end of day
+	}
+	days, err := parseAge(s)
+	if err != nil || days == 0 {
+		return 0, false, fmt.Errorf("invalid expiry '%s' (date 2025-12-31, delay 30d/6m/1y or never)", s)
+	}
+	return now.AddDate(0, 0, days).Unix(), false, nil
+}
+
+// linkSettings builds share link settings from the flags set on cmd
+func linkSettings(cmd *cobra.Command, creating bool) (kdrive.ShareLinkSettings, error) {
+	var s kdrive.ShareLinkSettings
+
+	validUntil, never, err := parseExpiry(linkExpires, time.Now())
+	if err != nil {
+		return s, err
+	}
+	s.ValidUntil = validUntil
+	s.NoExpiry = never && !creating
+
+	s.Right = linkRight
+	s.Password = linkPassword
+	switch {
+	case s.Right == "" && s.Password != "":
+		s.Right = kdrive.LinkPassword
+	case s.Right == "" && creating:
+		s.Right = kdrive.LinkPublic
+	}
+	switch s.Right {
+	case "", kdrive.LinkPublic, kdrive.LinkInherit:
+	case kdrive.LinkPassword:
+		if s.Password == "" && creating {
+			return s, fmt.Errorf("--password is required for password-protected links")
+		}
+	default:
+		return s, fmt.Errorf("invalid --right '%s' (public, password, inherit)", s.Right)
+	}
+
+	if cmd.Flags().Changed("download") {
+		s.CanDownload = &linkDownload
+	}
+	if cmd.Flags().Changed("edit") {
+		s.CanEdit = &linkEdit
+	}
+	return s, nil
+}
+
+// linkTargets resolves the target arguments (after the command name) to entries,
+// with everything below directories when --recursive is set
+func linkTargets(cmd *cobra.Command, client *kdrive.Client, args []string) ([]fileInfo, error) {
+	ctx := cmd.Context()
+	raw, err := targetArgs(args)
+	if err != nil {
+		return nil, err
+	}
+	if len(raw) == 0 {
+		return nil, fmt.Errorf("no file given (argument, '-' or --from-file)")
+	}
+	targets, err := resolveTargets(ctx, client, raw)
+	if err != nil {
+		return nil, err
+	}
+
+	cp, err := openCheckpoint(cmd, args)
+	if err != nil {
+		return nil, err
+	}
+	files, err := collectFiles(ctx, client, targets, recursive, cp)
+	if err != nil {
+		return nil, err
+	}
+	finishCheckpoint(cp)
+	return applyTagFilter(files, recursive)
+}
+
+// existingLinks returns the entries carrying a share link among the targets and,
+// with --recursive, below them
+func existingLinks(ctx context.Context, client *kdrive.Client, args []string) ([]linkResult, error) {
+	raw, err := targetArgs(args)
+	if err != nil {
+		return nil, err
+	}
+	if len(raw) == 0 {
+		return nil, fmt.Errorf("no file given (argument, '-' or --from-file)")
+	}
+	targets, err := resolveTargets(ctx, client, raw)
+	if err != nil {
+		return nil, err
+	}
+
+	if !recursive {
+		var results []linkResult
+		for _, t := range targets {
+			link, err := client.GetShareLink(ctx, t.ID)
+			if errors.Is(err, kdrive.ErrNotFound) {
+				fmt.Fprintf(os.Stderr, "Warning: %s has no share link\n", t.Path)
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			results = append(results, linkResult{ID: t.ID, Type: t.Type, Path: t.Path, Link: link})
+		}
+		return results, nil
+	}
+
+	shares, err := collectShares(ctx, client, targets, true)
+	if err != nil {
+		return nil, err
+	}
+	var results []linkResult
+	for _, s := range shares {
+		results = append(results, linkResult{
+			ID:   s.FileID,
+			Type: s.Type,
+			Path: s.Path,
+			Link: &kdrive.ShareLink{URL: s.Who, FileID: s.FileID, Right: s.Right, ValidUntil: s.Expires},
+		})
+	}
+	return results, nil
+}
+
+// linkReport is where link commands print their summary: stderr when the CSV
+// export goes to stdout, so that it stays parsable
+func linkReport() io.Writer {
+	if linkCSV == "-" {
+		return os.Stderr
+	}
+	return os.Stdout
+}
+
+// printLinkResults prints the outcome of a link operation and exports it as CSV
+// with --csv, instead of the table when the CSV goes to stdout
+func printLinkResults(results []linkResult) error {
+	if linkCSV == "-" {
+		return writeLinkCSV(linkCSV, results)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "STATUS\tRIGHT\tEXPIRES\tURL\tID\tPATH")
+	for _, r := range results {
+		right, expires, url := "-", "-", "-"
+		if r.Link != nil {
+			right, expires, url = r.Link.Right, formatLinkExpiry(r.Link.ValidUntil), r.Link.URL
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%s\n", r.Status, right, expires, url, r.ID, r.Path)
+	}
+	w.Flush()
+
+	if linkCSV == "" {
+		return nil
+	}
+	return writeLinkCSV(linkCSV, results)
+}
+
+func formatLinkExpiry(validUntil int64) string {
+	if validUntil == 0 {
+		return "never"
+	}
+	return time.Unix(validUntil, 0).Format("2006-01-02")
+}
+
+// writeLinkCSV exports link results (path, URL...) to a CSV file, "-" for stdout
+func writeLinkCSV(path string, results []linkResult) error {
+	out := os.Stdout
+	if path != "-" {
+		f, err := os.Create(path)
+		if err != nil {
+			return fmt.Errorf("cannot write CSV: %w", err)
+		}
+		defer f.Close()
+		out = f
+	}
+
+	w := csv.NewWriter(out)
+	w.Write([]string{"path", "id", "type", "url", "right", "expires", "status"})
+	for _, r := range results {
+		url, right, expires := "", "", ""
+		if r.Link != nil {
+			url, right = r.Link.URL, r.Link.Right
+			if r.Link.ValidUntil != 0 {
+				expires = time.Unix(r.Link.ValidUntil, 0).Format("2006-01-02")
+			}
+		}
+		w.Write([]string{r.Path, strconv.Itoa(r.ID), r.Type, url, right, expires, r.Status})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return fmt.Errorf("cannot write CSV: %w", err)
+	}
+	if path != "-" {
+		fmt.Fprintf(os.Stderr, "Links exported to %s\n", path)
+	}
+	return nil
+}
+
+// linkSummary prints the status counts and returns an error if any entry failed
+func linkSummary(results []linkResult) error {
+	counts := make(map[string]int)
+	failed := 0
+	for _, r := range results {
+		if strings.HasPrefix(r.Status, "error") {
+			failed++
+			continue
+		}
+		counts[r.Status]++
+	}
+	var parts []string
+	for _, status := range []string{"created", "exists", "updated", "deleted"} {
+		if counts[status] > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", counts[status], status))
+		}
+	}
+	parts = append(parts, fmt.Sprintf("%d failed", failed))
+	fmt.Fprintf(linkReport(), "\nDone: %s\n", strings.Join(parts, ", "))
+	if failed > 0 {
+		return fmt.Errorf("%d share links could not be processed", failed)
+	}
+	return nil
+}
+
+var linkCmd = &cobra.Command{
+	Use:   "link",
+	Short: "Manage share links",
+}
+
+var linkCreateCmd = &cobra.Command{
+	Use:   "create <path_or_id_or_glob>...",
+	Short: "Create share links",
+	Long: `Create a share link on each target (with --recursive, on every entry below too,
+restricted by the filter flags). Entries already shared keep their link, reported as 'exists'.
+--csv exports the paths and URLs, e.g. to send them to clients.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		client := newClient()
+
+		settings, err := linkSettings(cmd, true)
+		if err != nil {
+			return err
+		}
+		files, err := linkTargets(cmd, client, args)
+		if err != nil {
+			return err
+		}
+
+		entry := newJournalEntry(cmd, args)
+		defer saveJournal(entry)
+
+		bar := newProgressBar(len(files), "Creating links")
+		results := make([]linkResult, 0, len(files))
+		for _, f := range files {
+			bar.Describe(truncateName(f.Name, 30))
+			r := linkResult{ID: f.ID, Type: f.Type, Path: f.DrivePath, Status: "created"}
+			r.Link, err = client.CreateShareLink(ctx, f.ID, settings)
+			if err != nil {
+				if ctx.Err() != nil {
+					return err
+				}
+				r.Status = "error: " + err.Error()
+				// Already shared: report the existing link
+				if errors.Is(err, kdrive.ErrConflict) {
+					if existing, getErr := client.GetShareLink(ctx, f.ID); getErr == nil {
+						r.Link, r.Status = existing, "exists"
+					}
+				}
+			} else {
+				entry.Add(journal.Operation{Kind: journal.LinkCreate, FileIDs: []int{f.ID}})
+			}
+			results = append(results, r)
+			bar.Add(1)
+		}
+		bar.Finish()
+
+		if err := printLinkResults(results); err != nil {
+			return err
+		}
+		return linkSummary(results)
+	},
+}
+
+var linkUpdateCmd = &cobra.Command{
+	Use:   "update <path_or_id_or_glob>...",
+	Short: "Change the settings of share links",
+	Long: `Change the right, password, expiry or download right of the share links of the targets
+(with --recursive, of every share link below them). Only the flags given are changed.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		client := newClient()
+
+		settings, err := linkSettings(cmd, false)
+		if err != nil {
+			return err
+		}
+		if body, _ := settings.MarshalJSON(); string(body) == "{}" {
+			return fmt.Errorf("nothing to change (--right, --password, --expires, --download, --edit)")
+		}
+
+		results, err := existingLinks(ctx, client, args)
+		if err != nil {
+			return err
+		}
+		if len(results) == 0 {
+			fmt.Fprintln(linkReport(), "No share links found")
+			return nil
+		}
+
+		entry := newJournalEntry(cmd, args)
+		defer saveJournal(entry)
+
+		for i := range results {
+			r := &results[i]
+			if err := client.UpdateShareLink(ctx, r.ID, settings); err != nil {
+				if ctx.Err() != nil {
+					return err
+				}
+				r.Status = "error: " + err.Error()
+				continue
+			}
+			r.Status = "updated"
+			entry.Add(journal.Operation{Kind: journal.LinkUpdate, FileIDs: []int{r.ID}})
+			if link, err := client.GetShareLink(ctx, r.ID); err == nil {
+				r.Link = link
+			}
+		}
+
+		if err := printLinkResults(results); err != nil {
+			return err
+		}
+		return linkSummary(results)
+	},
+}
+
+var linkDeleteCmd = &cobra.Command{
+	Use:   "delete <path_or_id_or_glob>...",
+	Short: "Delete share links",
+	Long: `Delete the share links of the targets (with --recursive, every share link below them).
+A deleted link cannot be restored: a new link gets a new URL.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		client := newClient()
+
+		results, err := existingLinks(ctx, client, args)
+		if err != nil {
+			return err
+		}
+		if len(results) == 0 {
+			fmt.Fprintln(linkReport(), "No share links found")
+			return nil
+		}
+
+		if linkDryRun {
+			for i := range results {
+				results[i].Status = "to delete"
+			}
+			if err := printLinkResults(results); err != nil {
+				return err
+			}
+			fmt.Fprintf(linkReport(), "\nDry run: %d share links would be deleted\n", len(results))
+			return nil
+		}
+
+		entry := newJournalEntry(cmd, args)
+		defer saveJournal(entry)
+
+		for i := range results {
+			r := &results[i]
+			r.Status = "deleted"
+			if err := client.DeleteShareLink(ctx, r.ID); err != nil {
+				if ctx.Err() != nil {
+					return err
+				}
+				r.Status = "error: " + err.Error()
+				continue
+			}
+			entry.Add(journal.Operation{Kind: journal.LinkDelete, FileIDs: []int{r.ID}})
+		}
+
+		if err := printLinkResults(results); err != nil {
+			return err
+		}
+		return linkSummary(results)
+	},
+}
+
+func init() {
+	for _, c := range []*cobra.Command{linkCreateCmd, linkUpdateCmd} {
+		c.Flags().StringVar(&linkPassword, "password", "", "Protect the links with a password")
+		c.Flags().StringVar(&linkExpires, "expires", "", "Expiry: date (2025-12-31) or delay (30d, 6m, 1y)")
+		c.Flags().StringVar(&linkRight, "right", "", "Who can open the links: public, password, inherit (drive users with access)")
+		c.Flags().BoolVar(&linkDownload, "download", true, "Allow downloads (--download=false to only preview)")
+		c.Flags().BoolVar(&linkEdit, "edit", false, "Allow visitors to edit")
+	}
+	linkUpdateCmd.Flags().Lookup("expires").Usage = "Expiry: date (2025-12-31), delay (30d, 6m, 1y) or never"
+
+	for _, c := range []*cobra.Command{linkCreateCmd, linkUpdateCmd, linkDeleteCmd} {
+		c.Flags().BoolVarP(&recursive, "recursive", "r", false, "Include every entry below the target directories")
+		c.Flags().StringVar(&linkCSV, "csv", "", "Export paths and URLs as CSV to `file` ('-' for stdout)")
+		c.Flags().BoolVar(&strict, "strict", false, "Fail if any directory cannot be listed")
+		addTargetFlags(c)
+	}
+	addTagFilterFlags(linkCreateCmd)
+	linkCreateCmd.Flags().BoolVar(&resume, "resume", false, "Resume an interrupted recursive listing")
+	linkDeleteCmd.Flags().BoolVar(&linkDryRun, "dry-run", false, "Show the links that would be deleted")
+
+	linkCmd.AddCommand(linkCreateCmd)
+	linkCmd.AddCommand(linkUpdateCmd)
+	linkCmd.AddCommand(linkDeleteCmd)
+	rootCmd.AddCommand(linkCmd)
+}
@@ -2,53 +2,114 @@ package cmd
 
 import (
 	"fmt"
+	"os"
 	"sort"
+	"text/tabwriter"
 	"time"
 
-	"github.com/gfaivre/ktools/internal/api"
+	"github.com/gfaivre/ktools/internal/directory"
+	"github.com/gfaivre/ktools/pkg/kdrive"
 	"github.com/spf13/cobra"
 )
 
+var lsLong bool
+
 var lsCmd = &cobra.Command{
-	Use:   "ls [path_or_id]",
+	Use:   "ls [path_or_id_or_glob...]",
 	Short: "List files in a directory",
-	Long:  "List files in a directory by ID or path (e.g. 'ls 3' or 'ls /Common documents/RH')",
-	Args:  cobra.MaximumNArgs(1),
+	Long: `List files in a directory by ID or path (e.g. 'ls 3' or 'ls /Common documents/RH').
+Several targets, remote globs ('Projects/*/Invoices') and '-' (targets read from stdin) are accepted;
+files are listed themselves. --ids prints only IDs, to pipe into another command.
+--long adds the size and the creator of each entry.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		ctx := cmd.Context()
-		client := api.NewClient(cfg)
+		client := newClient()
+
+		targets, err := resolveTargetArgs(ctx, client, args)
+		if err != nil {
+			return err
+		}
+
+		var users *directory.Directory
+		if lsLong && !listIDs {
+			users = userDirectory(ctx, client)
+		}
+
+		// Files given as targets are listed first, then each directory
+		var dirs []*kdrive.File
+		var files []kdrive.File
+		for _, t := range targets {
+			if t.Type == "dir" {
+				dirs = append(dirs, t)
+			} else {
+				files = append(files, *t)
+			}
+		}
+		if len(files) > 0 {
+			printFiles(files, users)
+		}
 
-		fileID := 1
-		if len(args) > 0 {
-			var err error
-			fileID, err = resolveFileID(ctx, client, args[0])
+		for i, d := range dirs {
+			children, err := client.ListFiles(ctx, d.ID)
 			if err != nil {
 				return err
 			}
+			if len(targets) > 1 && !listIDs {
+				if i > 0 || len(files) > 0 {
+					fmt.Println()
+				}
+				fmt.Printf("%s:\n", d.Path)
+			}
+			printFiles(children, users)
 		}
+		return nil
+	},
+}
 
-		files, err := client.ListFiles(ctx, fileID)
-		if err != nil {
-			return err
-		}
+// printFiles prints files sorted by name, or only their IDs with --ids. With
+// --long, creators are named from users (IDs when nil).
+func printFiles(files []kdrive.File, users *directory.Directory) {
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Name < files[j].Name
+	})
 
-		sort.Slice(files, func(i, j int) bool {
-			return files[i].Name < files[j].Name
-		})
+	if listIDs {
+		for _, f := range files {
+			fmt.Println(f.ID)
+		}
+		return
+	}
 
-		fmt.Printf("TYPE\tMODIFIED\t\tID\tNAME\n")
+	if lsLong {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "TYPE\tMODIFIED\tSIZE\tCREATOR\tID\tNAME")
 		for _, f := range files {
-			printFile(&f)
+			size := "-"
+			if f.Type != "dir" {
+				size = formatSize(f.Size)
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%s\n", f.Type,
+				time.Unix(f.LastModifiedAt, 0).Format("2006-01-02 15:04"),
+				size, users.UserName(f.CreatedBy), f.ID, f.Name)
 		}
-		return nil
-	},
+		w.Flush()
+		return
+	}
+
+	fmt.Printf("TYPE\tMODIFIED\t\tID\tNAME\n")
+	for _, f := range files {
+		printFile(&f)
+	}
 }
 
-func printFile(f *api.File) {
+func printFile(f *kdrive.File) {
 	modTime := time.Unix(f.LastModifiedAt, 0).Format("2006-01-02 15:04")
 	fmt.Printf("%s\t%s\t%d\t%s\n", f.Type, modTime, f.ID, f.Name)
 }
 
 func init() {
+	lsCmd.Flags().BoolVarP(&lsLong, "long", "l", false, "Show the size and creator of each entry")
+	lsCmd.Flags().BoolVar(&listIDs, "ids", false, "Only print IDs, one per line (for piping)")
+	addTargetFlags(lsCmd)
 	rootCmd.AddCommand(lsCmd)
 }
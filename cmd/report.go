@@ -8,8 +8,8 @@ import (
 	"text/tabwriter"
 	"time"
 
-	"github.com/gfaivre/ktools/internal/api"
 	"github.com/gfaivre/ktools/internal/logging"
+	"github.com/gfaivre/ktools/pkg/kdrive"
 	"github.com/spf13/cobra"
 )
 
@@ -19,8 +19,8 @@ var (
 	reportFiles    []int
 	reportFrom     int64
 	reportUntil    int64
-	reportUserID   int
-	reportUsers    []int
+	reportUserID   string
+	reportUsers    []string
 	reportTerms    string
 	reportWait     bool
 	reportDownload bool
@@ -43,7 +43,20 @@ var reportCreateCmd = &cobra.Command{
 		}
 
 		ctx := cmd.Context()
-		client := api.NewAdminClient(cfg)
+		client := newAdminClient()
+
+		users, err := resolveUserIDs(ctx, client, reportUsers)
+		if err != nil {
+			return err
+		}
+		userID := 0
+		if reportUserID != "" {
+			ids, err := resolveUserIDs(ctx, client, []string{reportUserID})
+			if err != nil {
+				return err
+			}
+			userID = ids[0]
+		}
 
 		now := time.Now()
 		from := reportFrom
@@ -55,14 +68,14 @@ var reportCreateCmd = &cobra.Command{
 			until = now.Unix()
 		}
 
-		opts := api.ReportOptions{
+		opts := kdrive.ReportOptions{
 			Actions: reportActions,
 			Depth:   reportDepth,
 			Files:   reportFiles,
 			From:    from,
 			Until:   until,
-			UserID:  reportUserID,
-			Users:   reportUsers,
+			UserID:  userID,
+			Users:   users,
 			Terms:   reportTerms,
 		}
 
@@ -125,38 +138,30 @@ var reportListCmd = &cobra.Command{
 		}
 
 		ctx := cmd.Context()
-		client := api.NewAdminClient(cfg)
+		client := newAdminClient()
 
 		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
 		fmt.Fprintln(w, "ID\tSTATUS\tSIZE\tCREATED\tDOWNLOAD URL")
 
-		page := 1
-		for {
-			reports, pages, err := client.ListReports(ctx, page)
+		for r, err := range client.Reports(ctx) {
 			if err != nil {
 				return err
 			}
-			for _, r := range reports {
-				url := r.DownloadURL
-				if url == "" {
-					url = "-"
-				}
-				size := r.Size
-				if size == "" || size == "0" {
-					size = "-"
-				}
-				fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n",
-					r.ID,
-					r.Status,
-					size,
-					time.Unix(r.CreatedAt, 0).Format("2006-01-02 15:04"),
-					url,
-				)
+			url := r.DownloadURL
+			if url == "" {
+				url = "-"
 			}
-			if page >= pages {
-				break
+			size := r.Size
+			if size == "" || size == "0" {
+				size = "-"
 			}
-			page++
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n",
+				r.ID,
+				r.Status,
+				size,
+				time.Unix(r.CreatedAt, 0).Format("2006-01-02 15:04"),
+				url,
+			)
 		}
 
 		return w.Flush()
@@ -178,7 +183,7 @@ var reportDeleteCmd = &cobra.Command{
 		}
 
 		ctx := cmd.Context()
-		client := api.NewAdminClient(cfg)
+		client := newAdminClient()
 
 		if err := client.DeleteReport(ctx, reportID); err != nil {
 			return err
@@ -199,7 +204,7 @@ var reportDeleteAllCmd = &cobra.Command{
 		}
 
 		ctx := cmd.Context()
-		client := api.NewAdminClient(cfg)
+		client := newAdminClient()
 
 		deleted := 0
 		seen := make(map[int]bool)
@@ -230,7 +235,7 @@ var reportDeleteAllCmd = &cobra.Command{
 }
 
 
-func downloadReport(ctx context.Context, client *api.Client, reportID int, output string) error {
+func downloadReport(ctx context.Context, client *kdrive.Client, reportID int, output string) error {
 	fmt.Fprintln(os.Stderr, "Downloading report...")
 	data, err := client.DownloadReport(ctx, reportID)
 	if err != nil {
@@ -260,7 +265,7 @@ func downloadReport(ctx context.Context, client *api.Client, reportID int, outpu
 	return nil
 }
 
-func printReport(r *api.Report, exportURL string) {
+func printReport(r *kdrive.Report, exportURL string) {
 	size := r.Size
 	if size == "" || size == "0" {
 		size = "-"
@@ -280,8 +285,8 @@ func init() {
 	reportCreateCmd.Flags().IntSliceVar(&reportFiles, "file", nil, "File IDs to include (repeatable, max 500)")
 	reportCreateCmd.Flags().Int64Var(&reportFrom, "from", 0, "Start timestamp (Unix)")
 	reportCreateCmd.Flags().Int64Var(&reportUntil, "until", 0, "End timestamp (Unix)")
-	reportCreateCmd.Flags().IntVar(&reportUserID, "user-id", 0, "Filter by single user ID")
-	reportCreateCmd.Flags().IntSliceVar(&reportUsers, "user", nil, "Filter by user IDs (repeatable)")
+	reportCreateCmd.Flags().StringVar(&reportUserID, "user-id", "", "Filter by single user (ID, email or name)")
+	reportCreateCmd.Flags().StringArrayVar(&reportUsers, "user", nil, "Filter by users (ID, email or name, repeatable)")
 	reportCreateCmd.Flags().StringVar(&reportTerms, "terms", "", "Search terms (min 3 chars)")
 	reportCreateCmd.Flags().BoolVarP(&reportWait, "wait", "w", false, "Wait for completion and print download URL")
 	reportCreateCmd.Flags().BoolVarP(&reportDownload, "download", "d", false, "Download the report after completion (implies --wait)")
@@ -2,19 +2,28 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/signal"
 	"syscall"
 
 	"github.com/gfaivre/ktools/internal/config"
+	"github.com/gfaivre/ktools/internal/journal"
 	"github.com/gfaivre/ktools/internal/logging"
+	"github.com/gfaivre/ktools/pkg/kdrive"
 	"github.com/spf13/cobra"
 )
 
 var cfg *config.Config
 var verbose bool
 
+var (
+	traceHTTP bool
+	recordDir string
+	replayDir string
+)
+
 var rootCmd = &cobra.Command{
 	Use:   "ktools",
 	Short: "CLI tool to manage files on Infomaniak kDrive",
@@ -26,12 +35,54 @@ var rootCmd = &cobra.Command{
 		if err != nil {
 			return err
 		}
-		return cfg.Validate()
+		cfg.Trace = traceHTTP
+		cfg.RecordDir = recordDir
+		cfg.ReplayDir = replayDir
+		if err := cfg.Validate(); err != nil {
+			return err
+		}
+
+		// Proxy, TLS and fixture errors are reported before the command runs
+		if transport, err = newTransport(); err != nil {
+			return fmt.Errorf("HTTP transport setup error: %w", err)
+		}
+		return nil
 	},
 }
 
 func init() {
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "Enable verbose output")
+	rootCmd.PersistentFlags().BoolVar(&traceHTTP, "trace", false, "Dump every HTTP request/response on stderr (credentials redacted)")
+	rootCmd.PersistentFlags().StringVar(&recordDir, "record", "", "Save HTTP exchanges as JSON fixtures in `dir`")
+	rootCmd.PersistentFlags().StringVar(&replayDir, "replay", "", "Serve HTTP exchanges from fixtures in `dir` instead of the network")
+}
+
+// Exit codes returned by ktools
+const (
+	exitError        = 1   // any other error
+	exitNotFound     = 3   // file, path, category or journal entry not found
+	exitUnauthorized = 4   // invalid or expired token
+	exitForbidden    = 5   // token lacks the required scope or rights
+	exitRateLimited  = 6   // still rate limited after retries
+	exitInterrupted  = 130 // interrupted by a signal
+)
+
+// exitCode maps an error returned by a command to the process exit code
+func exitCode(err error) int {
+	switch {
+	case errors.Is(err, context.Canceled):
+		return exitInterrupted
+	case errors.Is(err, kdrive.ErrUnauthorized):
+		return exitUnauthorized
+	case errors.Is(err, kdrive.ErrForbidden):
+		return exitForbidden
+	case errors.Is(err, kdrive.ErrNotFound), errors.Is(err, journal.ErrNotFound):
+		return exitNotFound
+	case errors.Is(err, kdrive.ErrRateLimited):
+		return exitRateLimited
+	default:
+		return exitError
+	}
 }
 
 func Execute() {
@@ -50,9 +101,13 @@ func Execute() {
 	logging.Debug("starting command execution")
 	err := rootCmd.ExecuteContext(ctx)
 	logging.Debug("command returned", "err", err)
+	savePathIndex()
 
 	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
+		if errors.Is(err, kdrive.ErrUnauthorized) {
+			fmt.Fprintln(os.Stderr, "Check the token in ~/.config/ktools/config.yaml (api_token, admin_token) or KTOOLS_API_TOKEN")
+		}
+		os.Exit(exitCode(err))
 	}
 }
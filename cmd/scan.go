@@ -3,11 +3,12 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"path"
 	"sort"
 	"text/tabwriter"
 
-	"github.com/gfaivre/ktools/internal/api"
 	"github.com/gfaivre/ktools/internal/logging"
+	"github.com/gfaivre/ktools/pkg/kdrive"
 	"github.com/spf13/cobra"
 )
 
@@ -22,81 +23,88 @@ var (
 type dirStats struct {
 	ID        int
 	Name      string
+	Path      string
 	FileCount int
 	Size      int64
 	Depth     int
 }
 
+// scanAggregate accumulates directory stats during a streaming walk
+type scanAggregate struct {
+	Dirs       map[int]*dirStats `json:"dirs"`
+	TotalFiles int               `json:"total_files"`
+	TotalDirs  int               `json:"total_dirs"`
+	TotalSize  int64             `json:"total_size"`
+}
+
+// dir returns the stats of a directory, registering it if needed
+func (a *scanAggregate) dir(id int) *dirStats {
+	s, ok := a.Dirs[id]
+	if !ok {
+		s = &dirStats{ID: id}
+		a.Dirs[id] = s
+	}
+	return s
+}
+
+func (a *scanAggregate) add(f *kdrive.File) {
+	if f.Type == "dir" {
+		a.TotalDirs++
+		s := a.dir(f.ID)
+		s.Name = f.Name
+		s.Path = f.Path
+		s.Depth = f.Depth
+		return
+	}
+	a.TotalFiles++
+	a.TotalSize += f.Size
+	parent := a.dir(f.ParentID)
+	parent.FileCount++
+	parent.Size += f.Size
+}
+
 var scanCmd = &cobra.Command{
-	Use:   "scan [path_or_id]",
+	Use:   "scan [path_or_id_or_glob...]",
 	Short: "Find directories with many files",
-	Long:  "Scan directories and report those containing many files (direct children only)",
-	Args:  cobra.MaximumNArgs(1),
+	Long: `Scan directories and report those containing many files (direct children only).
+Several targets, remote globs ('Projects/*/Invoices', '**/archive') and '-' (targets read from stdin) are accepted.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		ctx := cmd.Context()
-		client := api.NewClient(cfg)
+		client := newClient()
 
-		// Resolve starting point
-		arg := ""
-		if len(args) > 0 {
-			arg = args[0]
-		}
-		startID, startName, err := resolveStartPath(ctx, client, arg)
+		targets, err := resolveTargetArgs(ctx, client, args)
 		if err != nil {
 			return err
 		}
 
-		logging.Debug("starting scan", "startID", startID, "startName", startName)
-
-		// Collect directory stats during scan
-		stats := make(map[int]*dirStats)
+		logging.Debug("starting scan", "targets", len(targets))
 
-		progress := func(dirName string, fileCount int) {
-			fmt.Fprintf(os.Stderr, "\r\033[KScanning: %s (%d files found)", truncateName(dirName, 40), fileCount)
-		}
-
-		files, err := client.ListFilesRecursiveWithProgress(ctx, startID, startName, progress)
-		fmt.Fprintln(os.Stderr)
+		cp, err := openCheckpoint(cmd, args)
 		if err != nil {
 			return err
 		}
 
-		logging.Debug("scan completed", "totalFiles", len(files))
-
-		// Build stats: count files per parent directory
-		// First pass: register all directories
-		for _, f := range files {
-			if f.Type == "dir" {
-				stats[f.ID] = &dirStats{
-					ID:    f.ID,
-					Name:  f.Name,
-					Depth: f.Depth,
-				}
+		// Aggregate per-directory stats while the trees are listed
+		agg := &scanAggregate{Dirs: map[int]*dirStats{}}
+		for _, t := range targets {
+			if t.Type == "dir" {
+				agg.Dirs[t.ID] = &dirStats{ID: t.ID, Name: t.Name, Path: t.Path}
+			} else if _, ok := agg.Dirs[t.ParentID]; !ok {
+				// Files given as targets are counted in their parent directory
+				dir := path.Dir(t.Path)
+				agg.Dirs[t.ParentID] = &dirStats{ID: t.ParentID, Name: path.Base(dir), Path: dir}
 			}
 		}
-
-		// Add root directory
-		stats[startID] = &dirStats{
-			ID:   startID,
-			Name: startName,
+		err = streamTree(ctx, client, cp, targets, walkOptions(), agg, agg.add)
+		if err != nil {
+			return err
 		}
+		finishCheckpoint(cp)
 
-		// Second pass: count files and size per parent
-		var totalFiles int
-		var totalDirs int
-		var totalSize int64
-		for _, f := range files {
-			if f.Type == "dir" {
-				totalDirs++
-			} else {
-				totalFiles++
-				totalSize += f.Size
-				if parent, ok := stats[f.ParentID]; ok {
-					parent.FileCount++
-					parent.Size += f.Size
-				}
-			}
-		}
+		logging.Debug("scan completed", "totalFiles", agg.TotalFiles)
+
+		stats := agg.Dirs
+		totalFiles, totalDirs, totalSize := agg.TotalFiles, agg.TotalDirs, agg.TotalSize
 
 		// Convert to slice (only dirs with files)
 		var results []dirStats
@@ -154,14 +162,14 @@ var scanCmd = &cobra.Command{
 		}
 
 		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
-		fmt.Fprintln(w, "FILES\tSIZE\t%\tID\tNAME")
+		fmt.Fprintln(w, "FILES\tSIZE\t%\tID\tPATH")
 		for _, r := range filtered {
 			var pct float64
 			if totalSize > 0 {
 				pct = float64(r.Size) / float64(totalSize) * 100
 			}
 			fmt.Fprintf(w, "%d\t%s\t%.1f%%\t%d\t%s\n",
-				r.FileCount, formatSize(r.Size), pct, r.ID, r.Name)
+				r.FileCount, formatSize(r.Size), pct, r.ID, r.Path)
 		}
 		w.Flush()
 
@@ -171,11 +179,14 @@ var scanCmd = &cobra.Command{
 	},
 }
 
-
 func init() {
 	scanCmd.Flags().IntVarP(&scanTop, "top", "n", 10, "Show top N directories (0 = unlimited)")
 	scanCmd.Flags().IntVarP(&scanThreshold, "threshold", "t", 100, "Minimum file count threshold")
 	scanCmd.Flags().BoolVarP(&scanAll, "all", "a", false, "Show all directories (no filtering)")
 	scanCmd.Flags().StringVarP(&scanSort, "sort", "s", "size", "Sort by: size, files")
+	scanCmd.Flags().BoolVar(&resume, "resume", false, "Resume an interrupted scan from its checkpoint")
+	scanCmd.Flags().BoolVar(&strict, "strict", false, "Fail if any directory cannot be listed")
+	addWalkFlags(scanCmd)
+	addTargetFlags(scanCmd)
 	rootCmd.AddCommand(scanCmd)
 }
@@ -0,0 +1,417 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/gfaivre/ktools/internal/journal"
+	"github.com/gfaivre/ktools/internal/logging"
+	"github.com/gfaivre/ktools/pkg/kdrive"
+	"github.com/spf13/cobra"
+)
+
+var (
+	sharesLinksOnly bool
+	sharesKinds     []string
+	revokeKinds     []string
+	revokeFlagged   bool
+	revokeDryRun    bool
+)
+
+// Share kinds
+const (
+	shareLink       = "link"
+	shareUser       = "user"
+	shareTeam       = "team"
+	shareInvitation = "invitation"
+)
+
+// share is a share link, or a user/team/invitation access granted on a file
+// or directory beyond what its parent grants
+type share struct {
+	Kind    string
+	FileID  int
+	Type    string // of the shared file
+	Path    string
+	ShareID int    // user, team or invitation ID (0 for links)
+	Who     string // link URL, user/invitation email or team name
+	Right   string
+	Expires int64 // links only, 0 = never
+	Issues  []string
+}
+
+// collectShares walks the targets and gathers their share links and, unless
+// linksOnly is set, the user, team and invitation shares of every entry
+func collectShares(ctx context.Context, client *kdrive.Client, targets []*kdrive.File, linksOnly bool) ([]share, error) {
+	var shares []share
+	var entries []*kdrive.File
+
+	// Links of the targets themselves (walks only report what is below them)
+	for _, t := range targets {
+		link, err := client.GetShareLink(ctx, t.ID)
+		if err != nil && !errors.Is(err, kdrive.ErrNotFound) {
+			return nil, err
+		}
+		if link != nil {
+			shares = append(shares, linkShare(t, link))
+		}
+		entries = append(entries, t)
+	}
+
+	err := walkTargets(ctx, client, targets, []string{"sharelink"}, func(f *kdrive.File) {
+		if f.ShareLink != nil {
+			shares = append(shares, linkShare(f, f.ShareLink))
+		}
+		entries = append(entries, f)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if !linksOnly {
+		access, err := accessShares(ctx, client, entries)
+		if err != nil {
+			return nil, err
+		}
+		shares = append(shares, access...)
+	}
+
+	for i := range shares {
+		shares[i].Issues = shareIssues(&shares[i])
+	}
+	sort.SliceStable(shares, func(i, j int) bool {
+		return shares[i].Path < shares[j].Path
+	})
+	return shares, nil
+}
+
+func linkShare(f *kdrive.File, link *kdrive.ShareLink) share {
+	return share{
+		Kind:    shareLink,
+		FileID:  f.ID,
+		Type:    f.Type,
+		Path:    f.Path,
+		Who:     link.URL,
+		Right:   link.Right,
+		Expires: link.ValidUntil,
+	}
+}
+
+// accessShares returns the access granted on each entry and not on its parent.
+// The parents of the targets are read too, so that their inherited access is not
+// reported as shared on the targets.
+func accessShares(ctx context.Context, client *kdrive.Client, entries []*kdrive.File) ([]share, error) {
+	accesses, err := fetchAccess(ctx, client, entries)
+	if err != nil {
+		return nil, err
+	}
+
+	var shares []share
+	for _, e := range entries {
+		access, ok := accesses[e.ID]
+		if !ok || e.ID == kdrive.RootID {
+			continue
+		}
+		parent := accesses[e.ParentID]
+		shares = append(shares, grantedShares(e, access, parent)...)
+	}
+	return shares, nil
+}
+
+// grantedShares returns the grants of access missing from (or with other
+// rights in) parent. Everything is returned when parent is unknown.
+func grantedShares(f *kdrive.File, access, parent *kdrive.FileAccess) []share {
+	inherited := make(map[string]bool)
+	if parent != nil {
+		for _, u := range parent.Users {
+			inherited[fmt.Sprintf("%s:%d:%s", shareUser, u.ID, u.Right)] = true
+		}
+		for _, t := range parent.Teams {
+			inherited[fmt.Sprintf("%s:%d:%s", shareTeam, t.ID, t.Right)] = true
+		}
+		for _, inv := range parent.Invitations {
+			inherited[fmt.Sprintf("%s:%d:%s", shareInvitation, inv.ID, inv.Right)] = true
+		}
+	}
+
+	var shares []share
+	add := func(kind string, id int, who, right string) {
+		if !inherited[fmt.Sprintf("%s:%d:%s", kind, id, right)] {
+			shares = append(shares, share{Kind: kind, FileID: f.ID, Type: f.Type, Path: f.Path, ShareID: id, Who: who, Right: right})
+		}
+	}
+	for _, u := range access.Users {
+		add(shareUser, u.ID, u.Email, u.Right)
+	}
+	for _, t := range access.Teams {
+		add(shareTeam, t.ID, t.Name, t.Right)
+	}
+	for _, inv := range access.Invitations {
+		add(shareInvitation, inv.ID, inv.Email, inv.Right)
+	}
+	return shares
+}
+
+// shareIssues returns why a share is risky: public links without password or
+// expiry, editable links and shares to emails outside internal_domains
+func shareIssues(s *share) []string {
+	var issues []string
+	switch s.Kind {
+	case shareLink:
+		if s.Right == kdrive.LinkPublic {
+			issues = append(issues, "no password")
+		}
+		if s.Expires == 0 && s.Right != kdrive.LinkInherit {
+			issues = append(issues, "no expiry")
+		}
+	case shareInvitation:
+		issues = append(issues, "external invitation")
+	case shareUser:
+		if isExternalEmail(s.Who) {
+			issues = append(issues, "external user")
+		}
+	}
+	return issues
+}
+
+// isExternalEmail reports whether an email is outside the configured internal
+// domains (never when none is configured)
+func isExternalEmail(email string) bool {
+	if len(cfg.InternalDomains) == 0 || email == "" {
+		return false
+	}
+	_, domain, _ := strings.Cut(strings.ToLower(email), "@")
+	for _, d := range cfg.InternalDomains {
+		d = strings.ToLower(strings.TrimPrefix(d, "@"))
+		if domain == d || strings.HasSuffix(domain, "."+d) {
+			return false
+		}
+	}
+	return true
+}
+
+// filterShares keeps the shares of the given kinds (all when empty)
+func filterShares(shares []share, kinds []string) ([]share, error) {
+	if len(kinds) == 0 {
+		return shares, nil
+	}
+	wanted := make(map[string]bool)
+	for _, k := range kinds {
+		switch k {
+		case shareLink, shareUser, shareTeam, shareInvitation:
+			wanted[k] = true
+		default:
+			return nil, fmt.Errorf("invalid share kind '%s' (link, user, team, invitation)", k)
+		}
+	}
+	var filtered []share
+	for _, s := range shares {
+		if wanted[s.Kind] {
+			filtered = append(filtered, s)
+		}
+	}
+	return filtered, nil
+}
+
+func formatExpiry(s *share) string {
+	switch {
+	case s.Kind != shareLink:
+		return "-"
+	case s.Expires == 0:
+		return "never"
+	default:
+		return time.Unix(s.Expires, 0).Format("2006-01-02")
+	}
+}
+
+// printShares prints shares as a table, with their issues when withIssues is set
+func printShares(shares []share, withIssues bool) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	header := "KIND\tRIGHT\tEXPIRES\tWHO\tID\tPATH"
+	if withIssues {
+		header += "\tISSUES"
+	}
+	fmt.Fprintln(w, header)
+	for _, s := range shares {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%s", s.Kind, s.Right, formatExpiry(&s), s.Who, s.FileID, s.Path)
+		if withIssues {
+			fmt.Fprintf(w, "\t%s", strings.Join(s.Issues, ", "))
+		}
+		fmt.Fprintln(w)
+	}
+	w.Flush()
+}
+
+// runShares resolves the targets and collects their shares of the given kinds
+func runShares(cmd *cobra.Command, args []string, kinds []string) ([]share, error) {
+	ctx := cmd.Context()
+	client := newClient()
+
+	if _, err := filterShares(nil, kinds); err != nil {
+		return nil, err
+	}
+	// Access is only read for user, team or invitation shares
+	linksOnly := sharesLinksOnly || len(kinds) > 0 && !slices.ContainsFunc(kinds, func(k string) bool { return k != shareLink })
+
+	targets, err := resolveTargetArgs(ctx, client, args)
+	if err != nil {
+		return nil, err
+	}
+	logging.Debug("collecting shares", "targets", len(targets), "linksOnly", linksOnly)
+
+	shares, err := collectShares(ctx, client, targets, linksOnly)
+	if err != nil {
+		return nil, err
+	}
+	return filterShares(shares, kinds)
+}
+
+var sharesCmd = &cobra.Command{
+	Use:   "shares",
+	Short: "Audit share links and user/team shares",
+}
+
+var sharesLsCmd = &cobra.Command{
+	Use:   "ls [path_or_id_or_glob...]",
+	Short: "List share links and shares below a directory",
+	Long: `Walk a subtree (default: root) and list share links (right, expiry) and the users,
+teams and invitations granted access to a folder beyond what its parent grants.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		shares, err := runShares(cmd, args, sharesKinds)
+		if err != nil {
+			return err
+		}
+		if len(shares) == 0 {
+			fmt.Println("No shares found")
+			return nil
+		}
+		printShares(shares, false)
+		fmt.Printf("\nTotal: %d shares\n", len(shares))
+		return nil
+	},
+}
+
+var sharesAuditCmd = &cobra.Command{
+	Use:   "audit [path_or_id_or_glob...]",
+	Short: "Flag risky shares",
+	Long: `Walk a subtree (default: root) and flag share links without password or expiry,
+invitations and shares to users whose email is outside internal_domains.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		shares, err := runShares(cmd, args, sharesKinds)
+		if err != nil {
+			return err
+		}
+
+		var flagged []share
+		for _, s := range shares {
+			if len(s.Issues) > 0 {
+				flagged = append(flagged, s)
+			}
+		}
+		if len(flagged) == 0 {
+			fmt.Printf("No risky share found (%d shares checked)\n", len(shares))
+			return nil
+		}
+		printShares(flagged, true)
+		fmt.Printf("\nTotal: %d risky shares out of %d\n", len(flagged), len(shares))
+		return nil
+	},
+}
+
+var sharesRevokeCmd = &cobra.Command{
+	Use:   "revoke <path_or_id_or_glob>...",
+	Short: "Revoke share links and shares below a directory",
+	Long: `Walk a subtree and revoke its shares of the given kinds (default: share links),
+only those flagged by 'shares audit' with --flagged. Use --dry-run to review them first.
+Revoked links cannot be restored: a new link gets a new URL.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		client := newClient()
+		if len(args) == 0 && targetsFile == "" {
+			return fmt.Errorf("no target given (argument, '-' or --from-file)")
+		}
+
+		shares, err := runShares(cmd, args, revokeKinds)
+		if err != nil {
+			return err
+		}
+		if revokeFlagged {
+			var flagged []share
+			for _, s := range shares {
+				if len(s.Issues) > 0 {
+					flagged = append(flagged, s)
+				}
+			}
+			shares = flagged
+		}
+		if len(shares) == 0 {
+			fmt.Println("Nothing to revoke")
+			return nil
+		}
+
+		printShares(shares, revokeFlagged)
+		if revokeDryRun {
+			fmt.Printf("\nDry run: %d shares would be revoked\n", len(shares))
+			return nil
+		}
+
+		entry := newJournalEntry(cmd, args)
+		defer saveJournal(entry)
+
+		failed := 0
+		for _, s := range shares {
+			var err error
+			switch s.Kind {
+			case shareLink:
+				err = client.DeleteShareLink(ctx, s.FileID)
+			case shareUser:
+				err = client.RemoveUserAccess(ctx, s.FileID, s.ShareID)
+			case shareTeam:
+				err = client.RemoveTeamAccess(ctx, s.FileID, s.ShareID)
+			case shareInvitation:
+				err = client.DeleteInvitation(ctx, s.ShareID)
+			}
+			if err != nil {
+				if ctx.Err() != nil {
+					return err
+				}
+				fmt.Fprintf(os.Stderr, "Error: %s %s on %s: %v\n", s.Kind, s.Who, s.Path, err)
+				failed++
+				continue
+			}
+			entry.Add(journal.Operation{Kind: journal.ShareRevoke, Detail: s.Kind, FileIDs: []int{s.FileID}})
+		}
+
+		fmt.Printf("\nDone: %d revoked, %d failed\n", len(shares)-failed, failed)
+		if failed > 0 {
+			return fmt.Errorf("%d shares could not be revoked", failed)
+		}
+		return nil
+	},
+}
+
+func init() {
+	for _, c := range []*cobra.Command{sharesLsCmd, sharesAuditCmd, sharesRevokeCmd} {
+		c.Flags().BoolVar(&sharesLinksOnly, "links-only", false, "Only share links (skip the per-entry access lookups)")
+		c.Flags().BoolVar(&strict, "strict", false, "Fail if any directory cannot be listed")
+		addWalkFlags(c)
+		addTargetFlags(c)
+	}
+	sharesLsCmd.Flags().StringSliceVar(&sharesKinds, "kind", nil, "Only these kinds: link, user, team, invitation")
+	sharesAuditCmd.Flags().StringSliceVar(&sharesKinds, "kind", nil, "Only these kinds: link, user, team, invitation")
+	sharesRevokeCmd.Flags().StringSliceVar(&revokeKinds, "kind", []string{shareLink}, "Kinds to revoke: link, user, team, invitation")
+	sharesRevokeCmd.Flags().BoolVar(&revokeFlagged, "flagged", false, "Only revoke shares flagged by 'shares audit'")
+	sharesRevokeCmd.Flags().BoolVar(&revokeDryRun, "dry-run", false, "Show what would be revoked without applying it")
+
+	sharesCmd.AddCommand(sharesLsCmd)
+	sharesCmd.AddCommand(sharesAuditCmd)
+	sharesCmd.AddCommand(sharesRevokeCmd)
+	rootCmd.AddCommand(sharesCmd)
+}
@@ -9,8 +9,8 @@ import (
 	"text/tabwriter"
 	"time"
 
-	"github.com/gfaivre/ktools/internal/api"
 	"github.com/gfaivre/ktools/internal/logging"
+	"github.com/gfaivre/ktools/pkg/kdrive"
 	"github.com/spf13/cobra"
 )
 
@@ -22,30 +22,121 @@ var (
 
 // ageBucket represents an age distribution bucket
 type ageBucket struct {
-	label    string
-	minDays  int
-	maxDays  int // -1 for unlimited
-	count    int
-	size     int64
+	label   string
+	minDays int
+	maxDays int // -1 for unlimited
+	count   int
+	size    int64
+}
+
+// ageBuckets defines the age distribution ranges
+var ageBuckets = []ageBucket{
+	{label: "< 6 months", minDays: 0, maxDays: 182},
+	{label: "6m - 1 year", minDays: 182, maxDays: 365},
+	{label: "1 - 2 years", minDays: 365, maxDays: 730},
+	{label: "2 - 3 years", minDays: 730, maxDays: 1095},
+	{label: "3 - 5 years", minDays: 1095, maxDays: 1825},
+	{label: "> 5 years", minDays: 1825, maxDays: -1},
+}
+
+// staleAggregate accumulates the age distribution and the largest stale files
+// during a streaming walk. Only the top N stale files are kept (all of them
+// when --top is 0), the others are only counted.
+type staleAggregate struct {
+	Now          time.Time   `json:"now"`
+	Threshold    time.Time   `json:"threshold"`
+	Top          int         `json:"top"`
+	BucketCounts []int       `json:"bucket_counts"`
+	BucketSizes  []int64     `json:"bucket_sizes"`
+	TotalFiles   int         `json:"total_files"`
+	TotalSize    int64       `json:"total_size"`
+	StaleCount   int         `json:"stale_count"`
+	StaleSize    int64       `json:"stale_size"`
+	Files        []staleFile `json:"files"`
+}
+
+func newStaleAggregate(thresholdDays, top int) *staleAggregate {
+	now := time.Now()
+	return &staleAggregate{
+		Now:          now,
+		Threshold:    now.AddDate(0, 0, -thresholdDays),
+		Top:          top,
+		BucketCounts: make([]int, len(ageBuckets)),
+		BucketSizes:  make([]int64, len(ageBuckets)),
+	}
+}
+
+func (a *staleAggregate) add(f *kdrive.File) {
+	if f.Type == "dir" {
+		return
+	}
+
+	a.TotalFiles++
+	a.TotalSize += f.Size
+
+	modTime := time.Unix(f.LastModifiedAt, 0)
+	ageDays := int(a.Now.Sub(modTime).Hours() / 24)
+
+	// Update bucket distribution
+	for i, b := range ageBuckets {
+		if ageDays >= b.minDays && (b.maxDays == -1 || ageDays < b.maxDays) {
+			a.BucketCounts[i]++
+			a.BucketSizes[i] += f.Size
+			break
+		}
+	}
+
+	// Collect files older than threshold
+	if !modTime.Before(a.Threshold) || (staleMinSize > 0 && f.Size < staleMinSize) {
+		return
+	}
+	a.StaleCount++
+	a.StaleSize += f.Size
+	a.Files = append(a.Files, staleFile{
+		ID:         f.ID,
+		Name:       f.Name,
+		Path:       f.Path,
+		Size:       f.Size,
+		ModifiedAt: modTime,
+		AgeDays:    ageDays,
+		CreatedBy:  f.CreatedBy,
+	})
+
+	// Keep memory bounded: trim to the top N largest from time to time
+	if a.Top > 0 && len(a.Files) >= 2*a.Top+1024 {
+		a.trim()
+	}
+}
+
+// trim sorts stale files by size (largest first) and keeps the top N
+func (a *staleAggregate) trim() {
+	sort.Slice(a.Files, func(i, j int) bool {
+		return a.Files[i].Size > a.Files[j].Size
+	})
+	if a.Top > 0 && len(a.Files) > a.Top {
+		a.Files = a.Files[:a.Top]
+	}
 }
 
 // staleFile holds file info for stale report
 type staleFile struct {
 	ID         int
 	Name       string
+	Path       string
 	Size       int64
 	ModifiedAt time.Time
 	AgeDays    int
+	CreatedBy  int
 }
 
 var staleCmd = &cobra.Command{
-	Use:   "stale [path_or_id]",
+	Use:   "stale [path_or_id_or_glob...]",
 	Short: "Find old files for retention review",
-	Long:  "Scan directories and report files not modified since a given period (default: 2 years)",
-	Args:  cobra.MaximumNArgs(1),
+	Long: `Scan directories and report files not modified since a given period (default: 2 years).
+Several targets, remote globs ('Projects/*/Invoices', '**/*.tmp') and '-' (targets read from stdin) are accepted.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		ctx := cmd.Context()
-		client := api.NewClient(cfg)
+		client := newClient()
 
 		// Parse age threshold
 		thresholdDays, err := parseAge(staleAge)
@@ -53,86 +144,35 @@ var staleCmd = &cobra.Command{
 			return fmt.Errorf("invalid age format: %w", err)
 		}
 
-		// Resolve starting point
-		arg := ""
-		if len(args) > 0 {
-			arg = args[0]
-		}
-		startID, startName, err := resolveStartPath(ctx, client, arg)
+		targets, err := resolveTargetArgs(ctx, client, args)
 		if err != nil {
 			return err
 		}
 
-		logging.Debug("starting stale scan", "startID", startID, "thresholdDays", thresholdDays)
+		logging.Debug("starting stale scan", "targets", len(targets), "thresholdDays", thresholdDays)
 
-		// Scan files
-		progress := func(dirName string, fileCount int) {
-			fmt.Fprintf(os.Stderr, "\r\033[KScanning: %s (%d files found)", truncateName(dirName, 40), fileCount)
+		cp, err := openCheckpoint(cmd, args)
+		if err != nil {
+			return err
 		}
 
-		files, err := client.ListFilesRecursiveWithProgress(ctx, startID, startName, progress)
-		fmt.Fprintln(os.Stderr)
+		// Aggregate the distribution and stale files while the tree is listed
+		agg := newStaleAggregate(thresholdDays, staleTop)
+		err = streamTree(ctx, client, cp, targets, walkOptions(), agg, agg.add)
 		if err != nil {
 			return err
 		}
-
-		now := time.Now()
-		thresholdDate := now.AddDate(0, 0, -thresholdDays)
-
-		// Define age buckets
-		buckets := []ageBucket{
-			{label: "< 6 months", minDays: 0, maxDays: 182},
-			{label: "6m - 1 year", minDays: 182, maxDays: 365},
-			{label: "1 - 2 years", minDays: 365, maxDays: 730},
-			{label: "2 - 3 years", minDays: 730, maxDays: 1095},
-			{label: "3 - 5 years", minDays: 1095, maxDays: 1825},
-			{label: "> 5 years", minDays: 1825, maxDays: -1},
+		finishCheckpoint(cp)
+		agg.trim()
+
+		buckets := make([]ageBucket, len(ageBuckets))
+		copy(buckets, ageBuckets)
+		for i := range buckets {
+			buckets[i].count = agg.BucketCounts[i]
+			buckets[i].size = agg.BucketSizes[i]
 		}
-
-		// Collect stale files and build distribution
-		var staleFiles []staleFile
-		var totalFiles int
-		var totalSize int64
-
-		for _, f := range files {
-			if f.Type == "dir" {
-				continue
-			}
-
-			totalFiles++
-			totalSize += f.Size
-
-			modTime := time.Unix(f.LastModifiedAt, 0)
-			ageDays := int(now.Sub(modTime).Hours() / 24)
-
-			// Update bucket distribution
-			for i := range buckets {
-				if ageDays >= buckets[i].minDays && (buckets[i].maxDays == -1 || ageDays < buckets[i].maxDays) {
-					buckets[i].count++
-					buckets[i].size += f.Size
-					break
-				}
-			}
-
-			// Collect files older than threshold
-			if modTime.Before(thresholdDate) {
-				if staleMinSize > 0 && f.Size < staleMinSize {
-					continue
-				}
-				staleFiles = append(staleFiles, staleFile{
-					ID:         f.ID,
-					Name:       f.Name,
-					Size:       f.Size,
-					ModifiedAt: modTime,
-					AgeDays:    ageDays,
-				})
-			}
-		}
-
-		// Sort stale files by size (largest first)
-		sort.Slice(staleFiles, func(i, j int) bool {
-			return staleFiles[i].Size > staleFiles[j].Size
-		})
+		totalFiles, totalSize := agg.TotalFiles, agg.TotalSize
+		staleFiles := agg.Files
 
 		// Print age distribution
 		fmt.Println("Age distribution:")
@@ -163,35 +203,29 @@ var staleCmd = &cobra.Command{
 			return nil
 		}
 
-		// Limit output
+		// Already limited to the top N by the aggregate
 		displayed := staleFiles
-		if staleTop > 0 && len(displayed) > staleTop {
-			displayed = displayed[:staleTop]
-		}
-
-		var staleSize int64
-		for _, f := range staleFiles {
-			staleSize += f.Size
-		}
+		users := userDirectory(ctx, client)
 
 		w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
-		fmt.Fprintln(w, "AGE\tSIZE\tMODIFIED\tID\tNAME")
+		fmt.Fprintln(w, "AGE\tSIZE\tMODIFIED\tCREATOR\tID\tPATH")
 		for _, f := range displayed {
-			fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\n",
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%s\n",
 				formatAgeDays(f.AgeDays),
 				formatSize(f.Size),
 				f.ModifiedAt.Format("2006-01-02"),
+				users.UserName(f.CreatedBy),
 				f.ID,
-				f.Name)
+				f.Path)
 		}
 		w.Flush()
 
-		if len(staleFiles) > len(displayed) {
-			fmt.Printf("\n... and %d more files\n", len(staleFiles)-len(displayed))
+		if agg.StaleCount > len(displayed) {
+			fmt.Printf("\n... and %d more files\n", agg.StaleCount-len(displayed))
 		}
 
 		fmt.Printf("\nTotal: %d files, %s (out of %d files, %s)\n",
-			len(staleFiles), formatSize(staleSize), totalFiles, formatSize(totalSize))
+			agg.StaleCount, formatSize(agg.StaleSize), totalFiles, formatSize(totalSize))
 
 		return nil
 	},
@@ -244,5 +278,9 @@ func init() {
 	staleCmd.Flags().StringVarP(&staleAge, "age", "a", "2y", "Minimum age threshold (e.g., 2y, 6m, 90d)")
 	staleCmd.Flags().IntVarP(&staleTop, "top", "n", 20, "Show top N files (0 = unlimited)")
 	staleCmd.Flags().Int64VarP(&staleMinSize, "min-size", "m", 0, "Minimum file size in bytes")
+	staleCmd.Flags().BoolVar(&resume, "resume", false, "Resume an interrupted scan from its checkpoint")
+	staleCmd.Flags().BoolVar(&strict, "strict", false, "Fail if any directory cannot be listed")
+	addWalkFlags(staleCmd)
+	addTargetFlags(staleCmd)
 	rootCmd.AddCommand(staleCmd)
 }
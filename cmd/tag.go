@@ -6,20 +6,33 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"text/tabwriter"
+	"time"
 
-	"github.com/gfaivre/ktools/internal/api"
+	"github.com/gfaivre/ktools/internal/checkpoint"
+	"github.com/gfaivre/ktools/internal/journal"
 	"github.com/gfaivre/ktools/internal/logging"
+	"github.com/gfaivre/ktools/pkg/kdrive"
 	"github.com/schollz/progressbar/v3"
 	"github.com/spf13/cobra"
 )
 
 type fileInfo struct {
-	ID   int
-	Name string
+	ID         int
+	Name       string
+	Path       string // relative to the collected root ("" for the root itself)
+	DrivePath  string // absolute drive path
+	Type       string
+	Size       int64
+	ModifiedAt int64
+}
+
+func newFileInfo(f *kdrive.File, path string) fileInfo {
+	return fileInfo{ID: f.ID, Name: f.Name, Path: path, DrivePath: f.Path, Type: f.Type, Size: f.Size, ModifiedAt: f.LastModifiedAt}
 }
 
 // resolveCategory resolves a category name or ID to both ID and name
-func resolveCategory(ctx context.Context, client *api.Client, nameOrID string) (int, string, error) {
+func resolveCategory(ctx context.Context, client *kdrive.Client, nameOrID string) (int, string, error) {
 	// If it's a numeric ID, fetch categories to get the name
 	if id, err := strconv.Atoi(nameOrID); err == nil {
 		categories, err := client.ListCategories(ctx)
@@ -50,32 +63,34 @@ func resolveCategory(ctx context.Context, client *api.Client, nameOrID string) (
 	return 0, "", fmt.Errorf("category '%s' not found", nameOrID)
 }
 
-// collectFiles collects file IDs and names, optionally recursively
-func collectFiles(ctx context.Context, client *api.Client, fileID int, recursive bool) ([]fileInfo, error) {
-	logging.Debug("collecting files", "fileID", fileID, "recursive", recursive)
-	rootFile, err := client.GetFile(ctx, fileID)
-	if err != nil {
-		logging.Debug("GetFile error", "err", err)
-		return nil, err
-	}
+// collectFiles collects the targets and, recursively, the files below the
+// directory targets. The recursive listing resumes from and is checkpointed to cp.
+func collectFiles(ctx context.Context, client *kdrive.Client, targets []*kdrive.File, recursive bool, cp *checkpoint.Checkpoint) ([]fileInfo, error) {
+	logging.Debug("collecting files", "targets", len(targets), "recursive", recursive)
 
-	files := []fileInfo{{ID: rootFile.ID, Name: rootFile.Name}}
+	files := make([]fileInfo, 0, len(targets))
+	for _, t := range targets {
+		files = append(files, newFileInfo(t, ""))
+	}
 
 	if recursive {
-		// Show scanning progress
-		progress := func(dirName string, fileCount int) {
-			fmt.Fprintf(os.Stderr, "\r\033[KScanning: %s (%d files found)", truncateName(dirName, 40), fileCount)
-		}
-
-		logging.Debug("starting recursive scan", "fileID", fileID)
-		children, err := client.ListFilesRecursiveWithProgress(ctx, fileID, rootFile.Name, progress)
-		fmt.Fprintln(os.Stderr) // Clear line
+		roots := walkRoots(targets)
+		logging.Debug("starting recursive scan", "roots", len(roots))
+		children, err := walkTree(ctx, client, cp, roots)
 		logging.Debug("recursive scan completed", "count", len(children), "err", err)
 		if err != nil {
 			return nil, err
 		}
-		for _, f := range children {
-			files = append(files, fileInfo{ID: f.ID, Name: f.Name})
+
+		// Targets nested in a walked directory are listed again by the walk
+		seen := make(map[int]bool, len(files))
+		for _, f := range files {
+			seen[f.ID] = true
+		}
+		for i := range children {
+			if !seen[children[i].ID] {
+				files = append(files, newFileInfo(&children[i], rootRelativePath(roots, children[i].Path)))
+			}
 		}
 	}
 
@@ -122,7 +137,7 @@ var tagListCmd = &cobra.Command{
 	Short: "List available categories",
 	RunE: func(cmd *cobra.Command, args []string) error {
 		ctx := cmd.Context()
-		client := api.NewClient(cfg)
+		client := newClient()
 
 		categories, err := client.ListCategories(ctx)
 		if err != nil {
@@ -138,128 +153,195 @@ var tagListCmd = &cobra.Command{
 
 var recursive bool
 
-var tagAddCmd = &cobra.Command{
-	Use:   "add <category> <file_or_path>",
-	Short: "Add a category to a file/directory",
-	Long:  "Add a category (name or ID) to a file/directory (by ID or path)",
-	Args:  cobra.ExactArgs(2),
-	RunE: func(cmd *cobra.Command, args []string) error {
-		ctx := cmd.Context()
-		client := api.NewClient(cfg)
-
-		categoryID, categoryName, err := resolveCategory(ctx, client, args[0])
-		if err != nil {
-			return err
-		}
-
-		fileID, err := resolveFileID(ctx, client, args[1])
-		if err != nil {
-			return err
-		}
-
-		files, err := collectFiles(ctx, client, fileID, recursive)
-		if err != nil {
-			return err
-		}
+// categoryOp describes the direction of a category update
+type categoryOp struct {
+	kind    string // journal.CategoryAdd or journal.CategoryRemove
+	verb    string // progress bar label
+	done    string // summary verb
+	skipped string // why unchanged files were skipped
+}
 
-		fileIDs, fileNames := buildFileMap(files)
+var (
+	opAddCategory    = categoryOp{kind: journal.CategoryAdd, verb: "Adding", done: "tagged", skipped: "already tagged"}
+	opRemoveCategory = categoryOp{kind: journal.CategoryRemove, verb: "Removing", done: "untagged", skipped: "not tagged"}
+)
 
-		bar := newProgressBar(len(files), fmt.Sprintf("Adding [%s]", categoryName))
-		var okCount, skipCount int
+// updateCategory sends a batched category update with a progress bar.
+// names is used for progress display and onResult observes each result; both may be nil.
+func updateCategory(ctx context.Context, client *kdrive.Client, kind string, categoryID int, fileIDs []int, label string, names map[int]string, onResult func(id int, changed bool, err error)) (*kdrive.BatchResult, error) {
+	bar := newProgressBar(len(fileIDs), label)
+	defer bar.Finish()
 
-		const batchSize = 50
-		for i := 0; i < len(fileIDs); i += batchSize {
-			end := i + batchSize
-			if end > len(fileIDs) {
-				end = len(fileIDs)
+	opts := kdrive.BatchOptions{
+		BatchSize: 50,
+		OnResult: func(id int, changed bool, err error) {
+			if name, ok := names[id]; ok {
+				bar.Describe(truncateName(name, 30))
 			}
-
-			results, err := client.AddCategoryToFiles(ctx, categoryID, fileIDs[i:end])
-			if err != nil {
-				fmt.Fprintln(os.Stderr)
-				return err
+			bar.Add(1)
+			if onResult != nil {
+				onResult(id, changed, err)
 			}
+		},
+	}
 
-			for _, r := range results {
-				name := fileNames[r.ID]
-				bar.Describe(truncateName(name, 30))
-				bar.Add(1)
-				if r.Result {
-					okCount++
-				} else {
-					skipCount++
-				}
-			}
-		}
+	if kind == journal.CategoryRemove {
+		return client.RemoveCategoryFromFilesBatched(ctx, categoryID, fileIDs, opts)
+	}
+	return client.AddCategoryToFilesBatched(ctx, categoryID, fileIDs, opts)
+}
 
-		fmt.Fprintf(os.Stderr, "\nDone: %d tagged, %d skipped (already tagged)\n", okCount, skipCount)
-		return nil
-	},
+// printBatchFailures lists the IDs a batched update could not apply to
+func printBatchFailures(failed []kdrive.BatchFailure, names map[int]string) {
+	if len(failed) == 0 {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "\nFailed (%d):\n", len(failed))
+	w := tabwriter.NewWriter(os.Stderr, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tERROR")
+	for _, f := range failed {
+		name := names[f.ID]
+		if name == "" {
+			name = "-"
+		}
+		fmt.Fprintf(w, "%d\t%s\t%v\n", f.ID, name, f.Err)
+	}
+	w.Flush()
 }
 
-var tagRmCmd = &cobra.Command{
-	Use:   "rm <category> <file_or_path>",
-	Short: "Remove a category from a file/directory",
-	Long:  "Remove a category (name or ID) from a file/directory (by ID or path)",
-	Args:  cobra.ExactArgs(2),
-	RunE: func(cmd *cobra.Command, args []string) error {
-		ctx := cmd.Context()
-		client := api.NewClient(cfg)
+// runTagUpdate implements tag add and tag rm
+func runTagUpdate(cmd *cobra.Command, args []string, op categoryOp) error {
+	ctx := cmd.Context()
+	client := newClient()
 
-		categoryID, categoryName, err := resolveCategory(ctx, client, args[0])
-		if err != nil {
-			return err
-		}
+	categoryID, categoryName, err := resolveCategory(ctx, client, args[0])
+	if err != nil {
+		return err
+	}
 
-		fileID, err := resolveFileID(ctx, client, args[1])
-		if err != nil {
-			return err
-		}
+	raw, err := targetArgs(args[1:])
+	if err != nil {
+		return err
+	}
+	if len(raw) == 0 {
+		return fmt.Errorf("no file given (argument, '-' or --from-file)")
+	}
+	targets, err := resolveTargets(ctx, client, raw)
+	if err != nil {
+		return err
+	}
 
-		files, err := collectFiles(ctx, client, fileID, recursive)
-		if err != nil {
-			return err
-		}
+	cp, err := openCheckpoint(cmd, args)
+	if err != nil {
+		return err
+	}
 
-		fileIDs, fileNames := buildFileMap(files)
+	files, err := collectFiles(ctx, client, targets, recursive, cp)
+	if err != nil {
+		return err
+	}
+	if recursive {
+		// Persist the completed walk so an interrupted update does not list the tree again
+		if err := cp.Save(); err != nil {
+			logging.Debug("checkpoint save failed", "err", err)
+		}
+	}
 
-		bar := newProgressBar(len(files), fmt.Sprintf("Removing [%s]", categoryName))
-		var okCount, skipCount int
+	files, err = applyTagFilter(files, recursive)
+	if err != nil {
+		return err
+	}
 
-		const batchSize = 50
-		for i := 0; i < len(fileIDs); i += batchSize {
-			end := i + batchSize
-			if end > len(fileIDs) {
-				end = len(fileIDs)
+	// Skip files already processed by an interrupted run
+	if len(cp.State.Done) > 0 {
+		done := make(map[int]bool, len(cp.State.Done))
+		for _, id := range cp.State.Done {
+			done[id] = true
+		}
+		var remaining []fileInfo
+		for _, f := range files {
+			if !done[f.ID] {
+				remaining = append(remaining, f)
 			}
+		}
+		fmt.Fprintf(os.Stderr, "Skipping %d files already processed\n", len(files)-len(remaining))
+		files = remaining
+	}
 
-			results, err := client.RemoveCategoryFromFiles(ctx, categoryID, fileIDs[i:end])
-			if err != nil {
-				fmt.Fprintln(os.Stderr)
-				return err
-			}
+	fileIDs, fileNames := buildFileMap(files)
 
-			for _, r := range results {
-				name := fileNames[r.ID]
-				bar.Describe(truncateName(name, 30))
-				bar.Add(1)
-				if r.Result {
-					okCount++
-				} else {
-					skipCount++
-				}
+	entry := newJournalEntry(cmd, args)
+	defer saveJournal(entry)
+
+	lastSave := time.Now()
+	onResult := func(id int, changed bool, err error) {
+		if err != nil || !recursive {
+			return
+		}
+		cp.State.Done = append(cp.State.Done, id)
+		if time.Since(lastSave) >= 30*time.Second {
+			if err := cp.Save(); err != nil {
+				logging.Debug("checkpoint save failed", "err", err)
 			}
+			lastSave = time.Now()
 		}
+	}
 
-		fmt.Fprintf(os.Stderr, "\nDone: %d untagged, %d skipped (not tagged)\n", okCount, skipCount)
-		return nil
+	res, err := updateCategory(ctx, client, op.kind, categoryID, fileIDs, fmt.Sprintf("%s [%s]", op.verb, categoryName), fileNames, onResult)
+	if res != nil {
+		entry.Record(op.kind, categoryID, categoryName, res.Changed)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr)
+		if recursive {
+			saveCheckpoint(cp)
+		}
+		return err
+	}
+	finishCheckpoint(cp)
+
+	fmt.Fprintf(os.Stderr, "\nDone: %d %s, %d skipped (%s), %d failed\n",
+		len(res.Changed), op.done, len(res.Unchanged), op.skipped, len(res.Failed))
+	printBatchFailures(res.Failed, fileNames)
+	if len(res.Failed) > 0 {
+		return fmt.Errorf("%d files could not be %s", len(res.Failed), op.done)
+	}
+	return nil
+}
+
+var tagAddCmd = &cobra.Command{
+	Use:   "add <category> <file_or_path_or_glob>...",
+	Short: "Add a category to files/directories",
+	Long: `Add a category (name or ID) to files/directories (by ID, path or remote glob).
+Use '-' or --from-file to read the targets, one per line, e.g. from 'ktools ls --ids'.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runTagUpdate(cmd, args, opAddCategory)
 	},
 }
 
+var tagRmCmd = &cobra.Command{
+	Use:   "rm <category> <file_or_path_or_glob>...",
+	Short: "Remove a category from files/directories",
+	Long: `Remove a category (name or ID) from files/directories (by ID, path or remote glob).
+Use '-' or --from-file to read the targets, one per line, e.g. from 'ktools tag find <category> --ids'.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runTagUpdate(cmd, args, opRemoveCategory)
+	},
+}
 
 func init() {
 	tagAddCmd.Flags().BoolVarP(&recursive, "recursive", "r", false, "Apply recursively to all children")
 	tagRmCmd.Flags().BoolVarP(&recursive, "recursive", "r", false, "Remove recursively from all children")
+	tagAddCmd.Flags().BoolVar(&resume, "resume", false, "Resume an interrupted recursive run")
+	tagRmCmd.Flags().BoolVar(&resume, "resume", false, "Resume an interrupted recursive run")
+	tagAddCmd.Flags().BoolVar(&strict, "strict", false, "Fail if any directory cannot be listed")
+	tagRmCmd.Flags().BoolVar(&strict, "strict", false, "Fail if any directory cannot be listed")
+	addTagFilterFlags(tagAddCmd)
+	addTagFilterFlags(tagRmCmd)
+	addTargetFlags(tagAddCmd)
+	addTargetFlags(tagRmCmd)
 
 	tagCmd.AddCommand(tagListCmd)
 	tagCmd.AddCommand(tagAddCmd)
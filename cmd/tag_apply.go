@@ -0,0 +1,380 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/gfaivre/ktools/internal/journal"
+	"github.com/gfaivre/ktools/pkg/kdrive"
+	"github.com/spf13/cobra"
+)
+
+// manifestRow is a single line of a tagging manifest
+type manifestRow struct {
+	Line     int
+	Target   string
+	Category string
+	Op       string
+
+	fileID     int
+	categoryID int
+	status     string
+}
+
+// manifestBatchKey groups rows sharing the same category and operation
+type manifestBatchKey struct {
+	categoryID int
+	op         string
+}
+
+// manifestGroup is the rows of one batch key, in manifest order
+type manifestGroup struct {
+	key  manifestBatchKey
+	rows []*manifestRow
+}
+
+// parseManifest reads CSV or JSON lines rows: path_or_id,category[,op]
+func parseManifest(r io.Reader) ([]*manifestRow, error) {
+	br := bufio.NewReader(r)
+	peek, _ := br.Peek(512)
+	if strings.HasPrefix(strings.TrimSpace(string(peek)), "{") {
+		return parseManifestJSON(br)
+	}
+	return parseManifestCSV(br)
+}
+
+func parseManifestCSV(r io.Reader) ([]*manifestRow, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.Comment = '#'
+	cr.TrimLeadingSpace = true
+
+	var rows []*manifestRow
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("manifest parse error: %w", err)
+		}
+		line, _ := cr.FieldPos(0)
+
+		if len(record) < 2 || len(record) > 3 {
+			return nil, fmt.Errorf("line %d: expected path_or_id,category[,op]", line)
+		}
+		// Skip optional header (path,category[,op])
+		if len(rows) == 0 && strings.EqualFold(strings.TrimSpace(record[0]), "path") && strings.EqualFold(strings.TrimSpace(record[1]), "category") {
+			continue
+		}
+
+		row := &manifestRow{Line: line, Target: record[0], Category: record[1]}
+		if len(record) == 3 {
+			row.Op = record[2]
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func parseManifestJSON(r io.Reader) ([]*manifestRow, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	var rows []*manifestRow
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		var raw struct {
+			Path     json.RawMessage `json:"path"`
+			ID       int             `json:"id"`
+			Category json.RawMessage `json:"category"`
+			Op       string          `json:"op"`
+		}
+		if err := json.Unmarshal([]byte(text), &raw); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		row := &manifestRow{Line: line, Target: jsonScalar(raw.Path), Category: jsonScalar(raw.Category), Op: raw.Op}
+		if row.Target == "" && raw.ID > 0 {
+			row.Target = strconv.Itoa(raw.ID)
+		}
+		if row.Target == "" || row.Category == "" {
+			return nil, fmt.Errorf("line %d: expected {\"path\": ..., \"category\": ...}", line)
+		}
+		rows = append(rows, row)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("manifest read error: %w", err)
+	}
+	return rows, nil
+}
+
+// jsonScalar returns a JSON string or number as plain text
+func jsonScalar(raw json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+	return strings.TrimSpace(string(raw))
+}
+
+// normalizeOp maps manifest operation aliases to "add" or "rm"
+func normalizeOp(op string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(op)) {
+	case "", "add", "+":
+		return "add", nil
+	case "rm", "remove", "del", "delete", "-":
+		return "rm", nil
+	default:
+		return "", fmt.Errorf("unknown op '%s' (add or rm)", op)
+	}
+}
+
+// findCategory looks up a category by ID or case-insensitive name. An all-digit
+// value matching no ID is looked up as a name ("2024").
+func findCategory(categories []kdrive.Category, nameOrID string) (*kdrive.Category, error) {
+	nameOrID = strings.TrimSpace(nameOrID)
+	if id, err := strconv.Atoi(nameOrID); err == nil {
+		for i := range categories {
+			if categories[i].ID == id {
+				return &categories[i], nil
+			}
+		}
+		if c, err := findCategoryByName(categories, nameOrID); err == nil {
+			return c, nil
+		}
+		return nil, fmt.Errorf("category %d not found", id)
+	}
+
+	return findCategoryByName(categories, nameOrID)
+}
+
+// findCategoryByName looks up a category by case-insensitive name only, for
+// names that may be all digits ("2024")
+func findCategoryByName(categories []kdrive.Category, name string) (*kdrive.Category, error) {
+	name = strings.TrimSpace(name)
+	for i := range categories {
+		if strings.EqualFold(categories[i].Name, name) {
+			return &categories[i], nil
+		}
+	}
+	return nil, fmt.Errorf("category '%s' not found", name)
+}
+
+var tagApplyCmd = &cobra.Command{
+	Use:   "apply <manifest>",
+	Short: "Apply categories from a manifest file",
+	Long: `Apply categories in bulk from a CSV or JSON lines manifest ('-' reads stdin).
+
+CSV rows:        path_or_id,category[,op]
+JSON lines rows: {"path": "Common documents/a.pdf", "category": "Confidential", "op": "add"}
+
+op is "add" (default) or "rm". Categories accept names or IDs. A first CSV line
+"path,category[,op]" is skipped as a header.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		client := newClient()
+
+		var in io.Reader = os.Stdin
+		if args[0] != "-" {
+			f, err := os.Open(args[0])
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			in = f
+		}
+
+		rows, err := parseManifest(in)
+		if err != nil {
+			return err
+		}
+		if len(rows) == 0 {
+			return fmt.Errorf("manifest is empty")
+		}
+
+		categories, err := client.ListCategories(ctx)
+		if err != nil {
+			return err
+		}
+
+		groups := resolveManifest(ctx, client, categories, rows)
+
+		entry := newJournalEntry(cmd, args)
+		err = applyManifest(ctx, client, groups, categories, entry)
+		saveJournal(entry)
+		if err != nil {
+			return err
+		}
+
+		return printManifestReport(rows, categories)
+	},
+}
+
+// resolveManifest resolves categories and targets, grouping rows per category
+// and op. Groups are in the order of their first row, so that adds and removes
+// of the same category are applied in manifest order.
+func resolveManifest(ctx context.Context, client *kdrive.Client, categories []kdrive.Category, rows []*manifestRow) []*manifestGroup {
+	var groups []*manifestGroup
+	byKey := make(map[manifestBatchKey]*manifestGroup)
+	resolved := make(map[string]int)
+
+	progress := stderrIsTerminal()
+	for i, row := range rows {
+		if progress {
+			fmt.Fprintf(os.Stderr, "\r\033[KResolving: %d/%d", i+1, len(rows))
+		}
+
+		op, err := normalizeOp(row.Op)
+		if err != nil {
+			row.status = "error: " + err.Error()
+			continue
+		}
+		row.Op = op
+
+		category, err := findCategory(categories, row.Category)
+		if err != nil {
+			row.status = "error: " + err.Error()
+			continue
+		}
+		row.categoryID = category.ID
+
+		target := strings.TrimSpace(row.Target)
+		fileID, ok := resolved[target]
+		if !ok {
+			fileID, err = resolveFileID(ctx, client, target)
+			if err != nil {
+				row.status = "error: " + err.Error()
+				continue
+			}
+			resolved[target] = fileID
+		}
+		row.fileID = fileID
+
+		key := manifestBatchKey{categoryID: row.categoryID, op: row.Op}
+		group, ok := byKey[key]
+		if !ok {
+			group = &manifestGroup{key: key}
+			byKey[key] = group
+			groups = append(groups, group)
+		}
+		group.rows = append(group.rows, row)
+	}
+	if progress {
+		fmt.Fprintln(os.Stderr)
+	}
+
+	return groups
+}
+
+// applyManifest sends batched category updates and records per-row status
+func applyManifest(ctx context.Context, client *kdrive.Client, groups []*manifestGroup, categories []kdrive.Category, entry *journal.Entry) error {
+	names := make(map[int]string, len(categories))
+	for _, c := range categories {
+		names[c.ID] = c.Name
+	}
+
+	for _, group := range groups {
+		key := group.key
+		// Deduplicate IDs within a group, several rows may target the same file
+		byID := make(map[int][]*manifestRow)
+		var ids []int
+		for _, row := range group.rows {
+			if _, ok := byID[row.fileID]; !ok {
+				ids = append(ids, row.fileID)
+			}
+			byID[row.fileID] = append(byID[row.fileID], row)
+		}
+
+		op := opAddCategory
+		if key.op == "rm" {
+			op = opRemoveCategory
+		}
+
+		res, err := updateCategory(ctx, client, op.kind, key.categoryID, ids, fmt.Sprintf("%s [%s]", op.verb, names[key.categoryID]), nil, nil)
+		if res != nil {
+			entry.Record(op.kind, key.categoryID, names[key.categoryID], res.Changed)
+			setRowStatus(byID, res.Changed, "ok")
+			setRowStatus(byID, res.Unchanged, "skipped")
+			for _, f := range res.Failed {
+				for _, row := range byID[f.ID] {
+					row.status = "error: " + f.Err.Error()
+				}
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func setRowStatus(byID map[int][]*manifestRow, ids []int, status string) {
+	for _, id := range ids {
+		for _, row := range byID[id] {
+			row.status = status
+		}
+	}
+}
+
+// printManifestReport prints one line per manifest row and returns an error if any row failed
+func printManifestReport(rows []*manifestRow, categories []kdrive.Category) error {
+	names := make(map[int]string, len(categories))
+	for _, c := range categories {
+		names[c.ID] = c.Name
+	}
+
+	var okCount, skipCount, errCount int
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "LINE\tOP\tCATEGORY\tID\tTARGET\tRESULT")
+	for _, row := range rows {
+		category := row.Category
+		if name, ok := names[row.categoryID]; ok {
+			category = name
+		}
+		id := "-"
+		if row.fileID > 0 {
+			id = strconv.Itoa(row.fileID)
+		}
+		status := row.status
+		if status == "" {
+			status = "error: no result returned"
+		}
+		switch {
+		case status == "ok":
+			okCount++
+		case status == "skipped":
+			skipCount++
+		default:
+			errCount++
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\n", row.Line, row.Op, category, id, row.Target, status)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Printf("\nDone: %d applied, %d skipped (unchanged), %d failed\n", okCount, skipCount, errCount)
+	if errCount > 0 {
+		return fmt.Errorf("%d manifest rows failed", errCount)
+	}
+	return nil
+}
+
+func init() {
+	tagCmd.AddCommand(tagApplyCmd)
+}
@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gfaivre/ktools/pkg/kdrive"
+)
+
+func TestParseManifest(t *testing.T) {
+	type row struct {
+		line            int
+		target, cat, op string
+	}
+	tests := []struct {
+		name    string
+		input   string
+		want    []row
+		wantErr string
+	}{
+		{
+			name:  "CSV with header",
+			input: "path,category,op\n/a.pdf,Invoices\n/b.pdf,Invoices,rm\n",
+			want:  []row{{2, "/a.pdf", "Invoices", ""}, {3, "/b.pdf", "Invoices", "rm"}},
+		},
+		{
+			name:  "CSV without header, comments and quotes",
+			input: "# manifest\n42, Urgent\n\"/Reports/Q1, Q2.xlsx\",2024\n",
+			want:  []row{{2, "42", "Urgent", ""}, {3, "/Reports/Q1, Q2.xlsx", "2024", ""}},
+		},
+		{
+			name:  "CSV first row named path only",
+			input: "path,Invoices\n",
+			want:  []row{{1, "path", "Invoices", ""}},
+		},
+		{
+			name:    "CSV missing category",
+			input:   "/a.pdf,Invoices\n/b.pdf\n",
+			wantErr: "line 2",
+		},
+		{
+			name:  "JSON lines",
+			input: "{\"path\": \"/a.pdf\", \"category\": \"Invoices\"}\n\n{\"id\": 42, \"category\": 7, \"op\": \"-\"}\n",
+			want:  []row{{1, "/a.pdf", "Invoices", ""}, {3, "42", "7", "-"}},
+		},
+		{
+			name:    "JSON without category",
+			input:   "{\"path\": \"/a.pdf\"}\n",
+			wantErr: "line 1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, err := parseManifest(strings.NewReader(tt.input))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(rows) != len(tt.want) {
+				t.Fatalf("got %d rows, want %d", len(rows), len(tt.want))
+			}
+			for i, w := range tt.want {
+				r := rows[i]
+				if r.Line != w.line || r.Target != w.target || r.Category != w.cat || r.Op != w.op {
+					t.Errorf("row %d = {%d %q %q %q}, want %v", i, r.Line, r.Target, r.Category, r.Op, w)
+				}
+			}
+		})
+	}
+}
+
+func TestFindCategory(t *testing.T) {
+	categories := []kdrive.Category{{ID: 2, Name: "Invoices"}, {ID: 5, Name: "2024"}, {ID: 2024, Name: "Other"}}
+	tests := []struct {
+		value string
+		id    int
+	}{
+		{"2", 2},
+		{" invoices ", 2},
+		{"2024", 2024}, // an ID wins over a name
+		{"5", 5},
+		{"3", 0},
+		{"Missing", 0},
+	}
+	for _, tt := range tests {
+		c, err := findCategory(categories, tt.value)
+		switch {
+		case tt.id == 0 && err == nil:
+			t.Errorf("findCategory(%q) = %d, want an error", tt.value, c.ID)
+		case tt.id != 0 && (err != nil || c.ID != tt.id):
+			t.Errorf("findCategory(%q) = %v, %v, want %d", tt.value, c, err, tt.id)
+		}
+	}
+
+	// An all-digit value matching no ID falls back to the names
+	c, err := findCategory(categories[:2], "2024")
+	if err != nil || c.ID != 5 {
+		t.Errorf("findCategory(2024) without ID 2024 = %v, %v, want 5", c, err)
+	}
+}
@@ -0,0 +1,331 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/gfaivre/ktools/internal/journal"
+	"github.com/gfaivre/ktools/pkg/kdrive"
+	"github.com/spf13/cobra"
+	"go.yaml.in/yaml/v3"
+)
+
+var (
+	tagColor     string
+	tagNewName   string
+	tagForce     bool
+	tagOutput    string
+	tagDryRun    bool
+	tagNoRecolor bool
+)
+
+var hexColorRe = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+// normalizeColor validates a #rrggbb color, accepting it with or without '#'
+func normalizeColor(color string) (string, error) {
+	if color == "" {
+		return "", nil
+	}
+	if !strings.HasPrefix(color, "#") {
+		color = "#" + color
+	}
+	if !hexColorRe.MatchString(color) {
+		return "", fmt.Errorf("invalid color '%s' (expected #rrggbb)", color)
+	}
+	return strings.ToLower(color), nil
+}
+
+// taxonomy is the YAML representation of a drive's category set
+type taxonomy struct {
+	Categories []taxonomyEntry `yaml:"categories"`
+}
+
+type taxonomyEntry struct {
+	Name  string `yaml:"name"`
+	Color string `yaml:"color"`
+}
+
+// categoryChange describes a rename or recolor for the journal
+func categoryChange(before, after *kdrive.Category) string {
+	var changes []string
+	if before.Name != after.Name {
+		changes = append(changes, fmt.Sprintf("name %s -> %s", before.Name, after.Name))
+	}
+	if !strings.EqualFold(before.Color, after.Color) {
+		changes = append(changes, fmt.Sprintf("color %s -> %s", before.Color, after.Color))
+	}
+	return strings.Join(changes, ", ")
+}
+
+var tagCreateCmd = &cobra.Command{
+	Use:   "create <name>",
+	Short: "Create a category",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		client := newClient()
+
+		color, err := normalizeColor(tagColor)
+		if err != nil {
+			return err
+		}
+		if color == "" {
+			return fmt.Errorf("--color required")
+		}
+
+		categories, err := client.ListCategories(ctx)
+		if err != nil {
+			return err
+		}
+		if existing, err := findCategoryByName(categories, args[0]); err == nil {
+			return fmt.Errorf("category '%s' already exists (ID %d)", existing.Name, existing.ID)
+		}
+
+		c, err := client.CreateCategory(ctx, args[0], color)
+		if err != nil {
+			return err
+		}
+		entry := newJournalEntry(cmd, args)
+		entry.Add(journal.Operation{Kind: journal.CategoryCreate, CategoryID: c.ID, CategoryName: c.Name, Detail: c.Color})
+
+		fmt.Printf("%d\t%s %s\t%s\n", c.ID, hexToANSI(c.Color), c.Color, c.Name)
+		saveJournal(entry)
+		return nil
+	},
+}
+
+var tagEditCmd = &cobra.Command{
+	Use:   "edit <category>",
+	Short: "Rename or recolor a category",
+	Long:  "Rename (--name) and/or recolor (--color) a category given by name or ID",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		client := newClient()
+
+		color, err := normalizeColor(tagColor)
+		if err != nil {
+			return err
+		}
+		if color == "" && tagNewName == "" {
+			return fmt.Errorf("nothing to change (use --name and/or --color)")
+		}
+
+		categories, err := client.ListCategories(ctx)
+		if err != nil {
+			return err
+		}
+		category, err := findCategory(categories, args[0])
+		if err != nil {
+			return err
+		}
+		if category.IsPredefined && tagNewName != "" {
+			return fmt.Errorf("category '%s' is predefined and cannot be renamed", category.Name)
+		}
+		if tagNewName != "" {
+			if other, err := findCategoryByName(categories, tagNewName); err == nil && other.ID != category.ID {
+				return fmt.Errorf("category '%s' already exists (ID %d)", other.Name, other.ID)
+			}
+		}
+
+		c, err := client.UpdateCategory(ctx, category.ID, tagNewName, color)
+		if err != nil {
+			return err
+		}
+		entry := newJournalEntry(cmd, args)
+		entry.Add(journal.Operation{Kind: journal.CategoryUpdate, CategoryID: c.ID, CategoryName: c.Name, Detail: categoryChange(category, c)})
+
+		fmt.Printf("%d\t%s %s\t%s\n", c.ID, hexToANSI(c.Color), c.Color, c.Name)
+		saveJournal(entry)
+		return nil
+	},
+}
+
+var tagDeleteCmd = &cobra.Command{
+	Use:   "delete <category>",
+	Short: "Delete a category",
+	Long:  "Delete a category given by name or ID. Refuses if files still carry it, unless --force is set.",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		client := newClient()
+
+		categories, err := client.ListCategories(ctx)
+		if err != nil {
+			return err
+		}
+		category, err := findCategory(categories, args[0])
+		if err != nil {
+			return err
+		}
+		if category.IsPredefined {
+			return fmt.Errorf("category '%s' is predefined and cannot be deleted", category.Name)
+		}
+
+		if !tagForce {
+			// The first file found is enough to refuse
+			for f, err := range client.FilesByCategory(ctx, category.ID) {
+				if err != nil {
+					return fmt.Errorf("cannot check files carrying '%s': %w", category.Name, err)
+				}
+				return fmt.Errorf("category '%s' is still used (e.g. by file %d '%s', use --force to delete anyway)", category.Name, f.ID, f.Name)
+			}
+		}
+
+		if err := client.DeleteCategory(ctx, category.ID); err != nil {
+			return err
+		}
+		entry := newJournalEntry(cmd, args)
+		entry.Add(journal.Operation{Kind: journal.CategoryDelete, CategoryID: category.ID, CategoryName: category.Name, Detail: category.Color})
+
+		fmt.Printf("Category %d (%s) deleted\n", category.ID, category.Name)
+		saveJournal(entry)
+		return nil
+	},
+}
+
+var tagExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export the category set as YAML",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		client := newClient()
+
+		categories, err := client.ListCategories(ctx)
+		if err != nil {
+			return err
+		}
+
+		var t taxonomy
+		for _, c := range categories {
+			t.Categories = append(t.Categories, taxonomyEntry{Name: c.Name, Color: c.Color})
+		}
+
+		var out io.Writer = os.Stdout
+		if tagOutput != "" && tagOutput != "-" {
+			f, err := os.Create(tagOutput)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			out = f
+		}
+
+		enc := yaml.NewEncoder(out)
+		enc.SetIndent(2)
+		if err := enc.Encode(t); err != nil {
+			return fmt.Errorf("YAML encoding error: %w", err)
+		}
+		if err := enc.Close(); err != nil {
+			return err
+		}
+
+		if out != os.Stdout {
+			fmt.Fprintf(os.Stderr, "Exported %d categories to %s\n", len(t.Categories), tagOutput)
+		}
+		return nil
+	},
+}
+
+var tagImportCmd = &cobra.Command{
+	Use:   "import <file>",
+	Short: "Import a category set from YAML",
+	Long:  "Create missing categories and recolor existing ones (matched by name) from a YAML file produced by 'tag export' ('-' reads stdin)",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		client := newClient()
+
+		var in io.Reader = os.Stdin
+		if args[0] != "-" {
+			f, err := os.Open(args[0])
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			in = f
+		}
+
+		var t taxonomy
+		if err := yaml.NewDecoder(in).Decode(&t); err != nil {
+			return fmt.Errorf("YAML parse error: %w", err)
+		}
+
+		categories, err := client.ListCategories(ctx)
+		if err != nil {
+			return err
+		}
+
+		journalEntry := newJournalEntry(cmd, args)
+		defer saveJournal(journalEntry)
+
+		var created, updated, unchanged int
+		for _, entry := range t.Categories {
+			if entry.Name == "" {
+				return fmt.Errorf("category without name in %s", args[0])
+			}
+			color, err := normalizeColor(entry.Color)
+			if err != nil {
+				return fmt.Errorf("category '%s': %w", entry.Name, err)
+			}
+
+			existing, err := findCategoryByName(categories, entry.Name)
+			switch {
+			case err != nil:
+				if color == "" {
+					return fmt.Errorf("category '%s': color required to create it", entry.Name)
+				}
+				fmt.Printf("create\t%s %s\t%s\n", hexToANSI(color), color, entry.Name)
+				created++
+				c := &kdrive.Category{Name: entry.Name, Color: color}
+				if !tagDryRun {
+					if c, err = client.CreateCategory(ctx, entry.Name, color); err != nil {
+						return fmt.Errorf("create '%s': %w", entry.Name, err)
+					}
+					journalEntry.Add(journal.Operation{Kind: journal.CategoryCreate, CategoryID: c.ID, CategoryName: c.Name, Detail: c.Color})
+				}
+				// A name listed twice is only created once
+				categories = append(categories, *c)
+			case color != "" && !tagNoRecolor && !strings.EqualFold(existing.Color, color):
+				fmt.Printf("recolor\t%s %s\t%s (was %s)\n", hexToANSI(color), color, existing.Name, existing.Color)
+				updated++
+				if !tagDryRun {
+					c, err := client.UpdateCategory(ctx, existing.ID, "", color)
+					if err != nil {
+						return fmt.Errorf("recolor '%s': %w", existing.Name, err)
+					}
+					journalEntry.Add(journal.Operation{Kind: journal.CategoryUpdate, CategoryID: c.ID, CategoryName: c.Name, Detail: categoryChange(existing, c)})
+				}
+			default:
+				unchanged++
+			}
+		}
+
+		prefix := "Done"
+		if tagDryRun {
+			prefix = "Dry run"
+		}
+		fmt.Fprintf(os.Stderr, "\n%s: %d created, %d recolored, %d unchanged\n", prefix, created, updated, unchanged)
+		return nil
+	},
+}
+
+func init() {
+	tagCreateCmd.Flags().StringVarP(&tagColor, "color", "c", "", "Category color (#rrggbb)")
+	tagEditCmd.Flags().StringVarP(&tagColor, "color", "c", "", "New color (#rrggbb)")
+	tagEditCmd.Flags().StringVarP(&tagNewName, "name", "n", "", "New name")
+	tagDeleteCmd.Flags().BoolVarP(&tagForce, "force", "f", false, "Delete even if files still carry the category")
+	tagExportCmd.Flags().StringVarP(&tagOutput, "output", "o", "", "Output file (default: stdout)")
+	tagImportCmd.Flags().BoolVar(&tagDryRun, "dry-run", false, "Show changes without applying them")
+	tagImportCmd.Flags().BoolVar(&tagNoRecolor, "no-recolor", false, "Only create missing categories, keep existing colors")
+
+	tagCmd.AddCommand(tagCreateCmd)
+	tagCmd.AddCommand(tagEditCmd)
+	tagCmd.AddCommand(tagDeleteCmd)
+	tagCmd.AddCommand(tagExportCmd)
+	tagCmd.AddCommand(tagImportCmd)
+}
@@ -0,0 +1,195 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// tagFilter restricts which collected entries a recursive tag operation applies to
+type tagFilter struct {
+	Type      string
+	Exts      []string
+	Name      string
+	OlderThan string
+	NewerThan string
+	MinSize   string
+	MaxSize   string
+	Excludes  []string
+}
+
+var tagFilterOpts tagFilter
+
+// compiledTagFilter is a tagFilter with parsed thresholds
+type compiledTagFilter struct {
+	tagFilter
+	olderThan time.Time
+	newerThan time.Time
+	minSize   int64
+	maxSize   int64
+}
+
+func addTagFilterFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&tagFilterOpts.Type, "type", "", "Only entries of this type: file, dir")
+	cmd.Flags().StringSliceVar(&tagFilterOpts.Exts, "ext", nil, "Only files with these extensions (repeatable, e.g. --ext pdf,docx)")
+	cmd.Flags().StringVar(&tagFilterOpts.Name, "name", "", "Only entries whose name matches this glob (e.g. '*invoice*')")
+	cmd.Flags().StringVar(&tagFilterOpts.OlderThan, "older-than", "", "Only entries not modified since (e.g. 2y, 6m, 90d)")
+	cmd.Flags().StringVar(&tagFilterOpts.NewerThan, "newer-than", "", "Only entries modified within (e.g. 2y, 6m, 90d)")
+	cmd.Flags().StringVar(&tagFilterOpts.MinSize, "min-size", "", "Only files at least this size (e.g. 500K, 10MB)")
+	cmd.Flags().StringVar(&tagFilterOpts.MaxSize, "max-size", "", "Only files at most this size (e.g. 500K, 10MB)")
+	cmd.Flags().StringArrayVar(&tagFilterOpts.Excludes, "exclude", nil, "Skip entries (and subtrees) whose relative path or name matches this glob (repeatable)")
+}
+
+func (f *tagFilter) active() bool {
+	return f.Type != "" || len(f.Exts) > 0 || f.Name != "" || f.OlderThan != "" || f.NewerThan != "" ||
+		f.MinSize != "" || f.MaxSize != "" || len(f.Excludes) > 0
+}
+
+func (f *tagFilter) compile(now time.Time) (*compiledTagFilter, error) {
+	c := &compiledTagFilter{tagFilter: *f}
+
+	switch f.Type {
+	case "", "file", "dir":
+	default:
+		return nil, fmt.Errorf("invalid --type '%s' (file or dir)", f.Type)
+	}
+
+	c.Exts = make([]string, len(f.Exts))
+	for i, ext := range f.Exts {
+		c.Exts[i] = "." + strings.ToLower(strings.TrimPrefix(ext, "."))
+	}
+
+	patterns := append([]string{f.Name}, f.Excludes...)
+	for _, p := range patterns {
+		if _, err := path.Match(p, ""); err != nil {
+			return nil, fmt.Errorf("invalid glob '%s': %w", p, err)
+		}
+	}
+
+	if f.OlderThan != "" {
+		days, err := parseAge(f.OlderThan)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --older-than: %w", err)
+		}
+		c.olderThan = now.AddDate(0, 0, -days)
+	}
+	if f.NewerThan != "" {
+		days, err := parseAge(f.NewerThan)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --newer-than: %w", err)
+		}
+		c.newerThan = now.AddDate(0, 0, -days)
+	}
+
+	var err error
+	if f.MinSize != "" {
+		if c.minSize, err = parseSize(f.MinSize); err != nil {
+			return nil, err
+		}
+	}
+	if f.MaxSize != "" {
+		if c.maxSize, err = parseSize(f.MaxSize); err != nil {
+			return nil, err
+		}
+	}
+
+	return c, nil
+}
+
+// excluded reports whether the entry or one of its ancestors matches an --exclude pattern
+func (c *compiledTagFilter) excluded(fi *fileInfo) bool {
+	if len(c.Excludes) == 0 || fi.Path == "" {
+		return false
+	}
+	segments := strings.Split(fi.Path, "/")
+	for _, pattern := range c.Excludes {
+		pattern = strings.Trim(pattern, "/")
+		for i := range segments {
+			if ok, _ := path.Match(pattern, segments[i]); ok {
+				return true
+			}
+			if ok, _ := path.Match(pattern, strings.Join(segments[:i+1], "/")); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (c *compiledTagFilter) match(fi *fileInfo) bool {
+	if c.excluded(fi) {
+		return false
+	}
+	if c.Type != "" && fi.Type != c.Type {
+		return false
+	}
+	isFile := fi.Type != "dir"
+	if len(c.Exts) > 0 {
+		if !isFile {
+			return false
+		}
+		ext := strings.ToLower(path.Ext(fi.Name))
+		found := false
+		for _, e := range c.Exts {
+			if e == ext {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if c.Name != "" {
+		if ok, _ := path.Match(strings.ToLower(c.Name), strings.ToLower(fi.Name)); !ok {
+			return false
+		}
+	}
+	modTime := time.Unix(fi.ModifiedAt, 0)
+	if !c.olderThan.IsZero() && !modTime.Before(c.olderThan) {
+		return false
+	}
+	if !c.newerThan.IsZero() && modTime.Before(c.newerThan) {
+		return false
+	}
+	if c.MinSize != "" && (!isFile || fi.Size < c.minSize) {
+		return false
+	}
+	if c.MaxSize != "" && (!isFile || fi.Size > c.maxSize) {
+		return false
+	}
+	return true
+}
+
+// applyTagFilter keeps only the collected entries matching the filter flags.
+// Filters only apply to recursive collections (files collected below the targets).
+func applyTagFilter(files []fileInfo, recursive bool) ([]fileInfo, error) {
+	if !tagFilterOpts.active() {
+		return files, nil
+	}
+	if !recursive {
+		return nil, fmt.Errorf("filters require --recursive")
+	}
+
+	filter, err := tagFilterOpts.compile(time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	var kept []fileInfo
+	for i := range files {
+		if filter.match(&files[i]) {
+			kept = append(kept, files[i])
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "Filtered: %d of %d entries selected\n", len(kept), len(files))
+	if len(kept) == 0 {
+		return nil, fmt.Errorf("no entries match the filters")
+	}
+	return kept, nil
+}
@@ -0,0 +1,345 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/gfaivre/ktools/internal/logging"
+	"github.com/gfaivre/ktools/pkg/kdrive"
+	"github.com/spf13/cobra"
+)
+
+var (
+	coverageCategory string
+	coverageTop      int
+	coverageSort     string
+	coverageDirs     bool
+	findType         string
+)
+
+// coverageStats holds tagged/untagged counters for a directory's direct children,
+// and file counters for its whole subtree
+type coverageStats struct {
+	ID           int
+	ParentID     int
+	Name         string
+	Path         string
+	Tagged       int
+	Untagged     int
+	TaggedSize   int64
+	UntaggedSize int64
+
+	SubtreeTagged   int
+	SubtreeUntagged int
+}
+
+func (s *coverageStats) pct() float64 {
+	return coveragePct(s.Tagged, s.Untagged)
+}
+
+func (s *coverageStats) subtreePct() float64 {
+	return coveragePct(s.SubtreeTagged, s.SubtreeUntagged)
+}
+
+func coveragePct(tagged, untagged int) float64 {
+	if tagged+untagged == 0 {
+		return 0
+	}
+	return float64(tagged) / float64(tagged+untagged) * 100
+}
+
+// hasCategory reports whether a file carries the given category (any category when categoryID is 0)
+func hasCategory(f *kdrive.File, categoryID int) bool {
+	if categoryID == 0 {
+		return len(f.Categories) > 0
+	}
+	for _, c := range f.Categories {
+		if c.ID == categoryID {
+			return true
+		}
+	}
+	return false
+}
+
+// relativePath returns path relative to the directory rootPath ("" for the root itself)
+func relativePath(rootPath, path string) string {
+	if rootPath == "/" {
+		return strings.TrimPrefix(path, "/")
+	}
+	return strings.TrimPrefix(strings.TrimPrefix(path, rootPath), "/")
+}
+
+// scanProgress prints the walker progress on stderr
+func scanProgress(dirName string, fileCount int) {
+	fmt.Fprintf(os.Stderr, "\r\033[KScanning: %s (%d files found)", truncateName(dirName, 40), fileCount)
+}
+
+// walkWithCategories lists a tree with file categories, tolerating unlistable directories unless --strict is set
+func walkWithCategories(ctx context.Context, client *kdrive.Client, startID int, startName string) ([]kdrive.File, error) {
+	opts := kdrive.WalkOptions{WithCategories: true, Strict: strict}
+	files, err := client.ListFilesRecursiveWithOptions(ctx, startID, startName, scanProgress, opts)
+	fmt.Fprintln(os.Stderr)
+
+	var partial *kdrive.PartialError
+	if errors.As(err, &partial) {
+		printPartialError(partial)
+		fmt.Fprintln(os.Stderr)
+		return files, nil
+	}
+	return files, err
+}
+
+var tagFindCmd = &cobra.Command{
+	Use:   "find <category> [path_or_id]",
+	Short: "Find files carrying a category",
+	Long:  "Walk a directory tree (default: root) and list files and folders carrying a category (name or ID)",
+	Args:  cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		client := newClient()
+
+		categories, err := client.ListCategories(ctx)
+		if err != nil {
+			return err
+		}
+		category, err := findCategory(categories, args[0])
+		if err != nil {
+			return err
+		}
+
+		arg := ""
+		if len(args) > 1 {
+			arg = args[1]
+		}
+		startID, startName, err := resolveStartPath(ctx, client, arg)
+		if err != nil {
+			return err
+		}
+
+		logging.Debug("starting category search", "category", category.ID, "startID", startID)
+
+		files, err := walkWithCategories(ctx, client, startID, startName)
+		if err != nil {
+			return err
+		}
+
+		var matches []kdrive.File
+		for _, f := range files {
+			if findType != "" && f.Type != findType {
+				continue
+			}
+			if hasCategory(&f, category.ID) {
+				matches = append(matches, f)
+			}
+		}
+
+		if len(matches) == 0 && !listIDs {
+			fmt.Printf("No files carry [%s]\n", category.Name)
+			return nil
+		}
+
+		sort.Slice(matches, func(i, j int) bool {
+			return matches[i].Path < matches[j].Path
+		})
+
+		if listIDs {
+			for _, f := range matches {
+				fmt.Println(f.ID)
+			}
+			return nil
+		}
+
+		var totalSize int64
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "TYPE\tSIZE\tID\tPATH")
+		for _, f := range matches {
+			size := "-"
+			if f.Type != "dir" {
+				size = formatSize(f.Size)
+				totalSize += f.Size
+			}
+			fmt.Fprintf(w, "%s\t%s\t%d\t%s\n", f.Type, size, f.ID, f.Path)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+
+		fmt.Printf("\nTotal: %d entries carry [%s], %s\n", len(matches), category.Name, formatSize(totalSize))
+		return nil
+	},
+}
+
+var tagCoverageCmd = &cobra.Command{
+	Use:   "coverage [path_or_id]",
+	Short: "Report tagged vs untagged content per directory",
+	Long: `Walk a directory tree (default: root) and report, for each directory, how many of its files carry
+a category (any category, or --category). TAGGED to COVERAGE count the files directly in the directory,
+SUBTREE counts every file below it. --untagged-dirs lists the folders not carrying the category.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		client := newClient()
+
+		categoryID := 0
+		label := "any category"
+		if coverageCategory != "" {
+			categories, err := client.ListCategories(ctx)
+			if err != nil {
+				return err
+			}
+			category, err := findCategory(categories, coverageCategory)
+			if err != nil {
+				return err
+			}
+			categoryID = category.ID
+			label = "[" + category.Name + "]"
+		}
+
+		arg := ""
+		if len(args) > 0 {
+			arg = args[0]
+		}
+		startID, startName, err := resolveStartPath(ctx, client, arg)
+		if err != nil {
+			return err
+		}
+
+		files, err := walkWithCategories(ctx, client, startID, startName)
+		if err != nil {
+			return err
+		}
+
+		startPath, err := client.FilePath(ctx, startID)
+		if err != nil {
+			startPath = startName
+		}
+
+		stats := map[int]*coverageStats{startID: {ID: startID, Name: startName, Path: startPath}}
+		for _, f := range files {
+			if f.Type == "dir" {
+				stats[f.ID] = &coverageStats{ID: f.ID, ParentID: f.ParentID, Name: f.Name, Path: f.Path}
+			}
+		}
+
+		var total coverageStats
+		var untaggedDirs []string
+		for _, f := range files {
+			parent, ok := stats[f.ParentID]
+			if !ok {
+				continue
+			}
+			if f.Type == "dir" {
+				if !hasCategory(&f, categoryID) {
+					untaggedDirs = append(untaggedDirs, f.Path)
+				}
+				continue
+			}
+			tagged := hasCategory(&f, categoryID)
+			if tagged {
+				parent.Tagged++
+				parent.TaggedSize += f.Size
+				total.Tagged++
+				total.TaggedSize += f.Size
+			} else {
+				parent.Untagged++
+				parent.UntaggedSize += f.Size
+				total.Untagged++
+				total.UntaggedSize += f.Size
+			}
+			// Count the file in every directory up to the start directory
+			for dir := parent; dir != nil; dir = stats[dir.ParentID] {
+				if tagged {
+					dir.SubtreeTagged++
+				} else {
+					dir.SubtreeUntagged++
+				}
+				if dir.ID == startID {
+					break
+				}
+			}
+		}
+
+		var results []*coverageStats
+		for _, s := range stats {
+			if s.SubtreeTagged+s.SubtreeUntagged > 0 {
+				results = append(results, s)
+			}
+		}
+
+		switch coverageSort {
+		case "coverage":
+			sort.Slice(results, func(i, j int) bool {
+				return results[i].pct() < results[j].pct()
+			})
+		case "subtree":
+			sort.Slice(results, func(i, j int) bool {
+				return results[i].subtreePct() < results[j].subtreePct()
+			})
+		case "path":
+			sort.Slice(results, func(i, j int) bool {
+				return results[i].Path < results[j].Path
+			})
+		default: // "untagged"
+			sort.Slice(results, func(i, j int) bool {
+				return results[i].UntaggedSize > results[j].UntaggedSize
+			})
+		}
+
+		displayed := results
+		if coverageTop > 0 && len(displayed) > coverageTop {
+			displayed = displayed[:coverageTop]
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "TAGGED\tUNTAGGED\tTAGGED SIZE\tUNTAGGED SIZE\tCOVERAGE\tSUBTREE\tID\tPATH")
+		for _, s := range displayed {
+			fmt.Fprintf(w, "%d\t%d\t%s\t%s\t%.1f%%\t%d/%d (%.1f%%)\t%d\t%s\n",
+				s.Tagged, s.Untagged, formatSize(s.TaggedSize), formatSize(s.UntaggedSize), s.pct(),
+				s.SubtreeTagged, s.SubtreeTagged+s.SubtreeUntagged, s.subtreePct(), s.ID, s.Path)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+
+		if len(results) > len(displayed) {
+			fmt.Printf("\n... and %d more directories\n", len(results)-len(displayed))
+		}
+
+		var sizePct float64
+		if totalSize := total.TaggedSize + total.UntaggedSize; totalSize > 0 {
+			sizePct = float64(total.TaggedSize) / float64(totalSize) * 100
+		}
+		fmt.Printf("\nCoverage (%s): %d/%d files (%.1f%%), %s/%s (%.1f%%), %d untagged folders\n",
+			label, total.Tagged, total.Tagged+total.Untagged, total.pct(),
+			formatSize(total.TaggedSize), formatSize(total.TaggedSize+total.UntaggedSize), sizePct,
+			len(untaggedDirs))
+
+		if coverageDirs && len(untaggedDirs) > 0 {
+			sort.Strings(untaggedDirs)
+			fmt.Println("\nUntagged folders:")
+			for _, path := range untaggedDirs {
+				fmt.Println(path)
+			}
+		}
+		return nil
+	},
+}
+
+func init() {
+	tagFindCmd.Flags().StringVar(&findType, "type", "", "Only list entries of this type: file, dir")
+	tagFindCmd.Flags().BoolVar(&listIDs, "ids", false, "Only print IDs, one per line (for piping)")
+	tagFindCmd.Flags().BoolVar(&strict, "strict", false, "Fail if any directory cannot be listed")
+	tagCoverageCmd.Flags().BoolVar(&strict, "strict", false, "Fail if any directory cannot be listed")
+	tagCoverageCmd.Flags().StringVarP(&coverageCategory, "category", "c", "", "Measure coverage of a single category (default: any category)")
+	tagCoverageCmd.Flags().IntVarP(&coverageTop, "top", "n", 20, "Show top N directories (0 = unlimited)")
+	tagCoverageCmd.Flags().StringVarP(&coverageSort, "sort", "s", "untagged", "Sort by: untagged, coverage, subtree, path")
+	tagCoverageCmd.Flags().BoolVar(&coverageDirs, "untagged-dirs", false, "List the paths of the untagged folders")
+
+	tagCmd.AddCommand(tagFindCmd)
+	tagCmd.AddCommand(tagCoverageCmd)
+}
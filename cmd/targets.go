@@ -0,0 +1,206 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/gfaivre/ktools/pkg/kdrive"
+	"github.com/spf13/cobra"
+)
+
+var (
+	targetsFile string
+	listIDs     bool
+
+	// readTargetLines holds the targets read from stdin or --from-file, part of the checkpoint key
+	readTargetLines []string
+)
+
+// addTargetFlags registers --from-file on commands accepting several targets
+func addTargetFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&targetsFile, "from-file", "", "Read targets (IDs, paths or globs, one per line) from `file` ('-' for stdin)")
+}
+
+// targetArgs returns the raw targets: the arguments, with '-' replaced by the
+// lines of stdin, followed by the lines of --from-file
+func targetArgs(args []string) ([]string, error) {
+	var targets []string
+	stdinRead := false
+	readStdin := func() error {
+		if stdinRead {
+			return nil
+		}
+		stdinRead = true
+		lines, err := readTargets(os.Stdin)
+		if err != nil {
+			return fmt.Errorf("cannot read targets from stdin: %w", err)
+		}
+		targets = append(targets, lines...)
+		readTargetLines = append(readTargetLines, lines...)
+		return nil
+	}
+
+	for _, arg := range args {
+		if arg != "-" {
+			targets = append(targets, arg)
+			continue
+		}
+		if err := readStdin(); err != nil {
+			return nil, err
+		}
+	}
+
+	switch targetsFile {
+	case "":
+	case "-":
+		if err := readStdin(); err != nil {
+			return nil, err
+		}
+	default:
+		f, err := os.Open(targetsFile)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read targets: %w", err)
+		}
+		defer f.Close()
+		lines, err := readTargets(f)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read targets from %s: %w", targetsFile, err)
+		}
+		targets = append(targets, lines...)
+		readTargetLines = append(readTargetLines, lines...)
+	}
+	return targets, nil
+}
+
+// readTargets reads one target per line, skipping blank lines and # comments
+func readTargets(r io.Reader) ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines, scanner.Err()
+}
+
+// resolveTargets resolves IDs, paths, bookmarks and globs to files, with their
+// drive path, in order and without duplicates. Globs matching nothing and
+// folders a glob could not list (fatal with --strict) are reported on stderr;
+// no target at all is an error.
+func resolveTargets(ctx context.Context, client *kdrive.Client, args []string) ([]*kdrive.File, error) {
+	var targets []*kdrive.File
+	seen := make(map[int]bool)
+	addTarget := func(f *kdrive.File) {
+		if !seen[f.ID] {
+			seen[f.ID] = true
+			targets = append(targets, f)
+		}
+	}
+
+	for _, arg := range args {
+		arg, err := expandBookmark(ctx, client, arg)
+		if err != nil {
+			return nil, err
+		}
+
+		if id, err := strconv.Atoi(arg); err == nil {
+			f, err := client.GetFile(ctx, id)
+			if err != nil {
+				return nil, err
+			}
+			if f.Path, err = client.FilePath(ctx, id); err != nil {
+				return nil, err
+			}
+			addTarget(f)
+			continue
+		}
+
+		if !kdrive.HasGlob(arg) {
+			f, err := client.FindFileByPath(ctx, arg)
+			if err != nil {
+				return nil, err
+			}
+			addTarget(f)
+			continue
+		}
+
+		// Unlistable folders only drop the matches below them, unless --strict
+		matches, err := client.Glob(ctx, arg)
+		var partial *kdrive.PartialError
+		if errors.As(err, &partial) && !strict {
+			printPartialError(partial)
+		} else if err != nil {
+			return nil, err
+		}
+		if len(matches) == 0 {
+			fmt.Fprintf(os.Stderr, "Warning: no match for '%s'\n", arg)
+		}
+		for i := range matches {
+			addTarget(&matches[i])
+		}
+	}
+
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("no target to process")
+	}
+	return targets, nil
+}
+
+// resolveTargetArgs resolves the targets of a command: its arguments, stdin
+// and --from-file, defaulting to the drive root when none is given
+func resolveTargetArgs(ctx context.Context, client *kdrive.Client, args []string) ([]*kdrive.File, error) {
+	if len(args) == 0 && targetsFile == "" {
+		args = []string{"/"}
+	}
+	raw, err := targetArgs(args)
+	if err != nil {
+		return nil, err
+	}
+	return resolveTargets(ctx, client, raw)
+}
+
+// walkRoots returns the directories to walk from the targets, skipping those
+// nested in another target so nothing is walked twice
+func walkRoots(targets []*kdrive.File) []kdrive.WalkDir {
+	var roots []kdrive.WalkDir
+	for _, t := range targets {
+		if t.Type != "dir" {
+			continue
+		}
+		nested := false
+		for _, o := range targets {
+			if o.Type == "dir" && o.ID != t.ID && isBelow(t.Path, o.Path) {
+				nested = true
+				break
+			}
+		}
+		if !nested {
+			roots = append(roots, kdrive.WalkDir{ID: t.ID, Name: t.Name, Path: t.Path})
+		}
+	}
+	return roots
+}
+
+// isBelow reports whether path is inside the directory dir
+func isBelow(path, dir string) bool {
+	return dir == "/" && path != "/" || strings.HasPrefix(path, dir+"/")
+}
+
+// rootRelativePath returns path relative to the walk root containing it
+func rootRelativePath(roots []kdrive.WalkDir, path string) string {
+	for _, r := range roots {
+		if path == r.Path || isBelow(path, r.Path) {
+			return relativePath(r.Path, path)
+		}
+	}
+	return strings.TrimPrefix(path, "/")
+}
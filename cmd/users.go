@@ -0,0 +1,296 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/gfaivre/ktools/internal/directory"
+	"github.com/gfaivre/ktools/internal/logging"
+	"github.com/gfaivre/ktools/pkg/kdrive"
+	"github.com/spf13/cobra"
+)
+
+var directoryRefresh bool
+
+// loadDirectory returns the users and teams of the drive, from the local cache
+// when it is fresh (directory_ttl) unless --refresh is set
+func loadDirectory(ctx context.Context, client *kdrive.Client) (*directory.Directory, error) {
+	return fetchDirectory(ctx, client, directoryRefresh)
+}
+
+// fetchDirectory is loadDirectory, bypassing the cache when refresh is set
+func fetchDirectory(ctx context.Context, client *kdrive.Client, refresh bool) (*directory.Directory, error) {
+	cached := cfg.ReplayDir == "" // fixtures must not depend on local state
+	if cached && !refresh {
+		d, err := directory.Load(cfg.DriveID, cfg.DirectoryTTL)
+		if err != nil {
+			logging.Debug("directory cache ignored", "err", err)
+		}
+		if d != nil {
+			logging.Debug("directory cache loaded", "users", len(d.Users), "teams", len(d.Teams))
+			return d, nil
+		}
+	}
+
+	users, err := kdrive.Collect(client.Users(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("cannot list users: %w", err)
+	}
+	teams, err := client.ListTeams(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("cannot list teams: %w", err)
+	}
+	d := &directory.Directory{DriveID: cfg.DriveID, FetchedAt: time.Now(), Users: users, Teams: teams}
+
+	if cached {
+		if err := directory.Save(d); err != nil {
+			logging.Debug("directory cache not saved", "err", err)
+		}
+	}
+	return d, nil
+}
+
+// userDirectory is loadDirectory for display purposes: on failure user IDs
+// are shown instead of names
+func userDirectory(ctx context.Context, client *kdrive.Client) *directory.Directory {
+	d, err := loadDirectory(ctx, client)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: user names unavailable: %v\n", err)
+		return nil
+	}
+	return d
+}
+
+// resolveUser finds the single user matching an ID, email or display name
+func resolveUser(d *directory.Directory, query string) (*kdrive.DriveUser, error) {
+	matches := d.FindUsers(query)
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("unknown user '%s' (see 'ktools users ls')", query)
+	case 1:
+		return &matches[0], nil
+	}
+	names := make([]string, len(matches))
+	for i, u := range matches {
+		names[i] = fmt.Sprintf("%s (%d)", u.Email, u.ID)
+	}
+	return nil, fmt.Errorf("ambiguous user '%s': matches %s", query, strings.Join(names, ", "))
+}
+
+// resolveUserIDs converts --user values (IDs, emails or display names) to
+// user IDs. The directory is only loaded when a value is not an ID, and
+// refreshed once when a value matches no cached user (e.g. a new member).
+func resolveUserIDs(ctx context.Context, client *kdrive.Client, values []string) ([]int, error) {
+	var ids []int
+	var d *directory.Directory
+	fresh := directoryRefresh || cfg.ReplayDir != ""
+	for _, v := range values {
+		if id, err := strconv.Atoi(v); err == nil {
+			ids = append(ids, id)
+			continue
+		}
+		if d == nil {
+			var err error
+			if d, err = loadDirectory(ctx, client); err != nil {
+				return nil, err
+			}
+		}
+		if !fresh && len(d.FindUsers(v)) == 0 {
+			logging.Debug("user not in cached directory, refreshing", "query", v)
+			var err error
+			if d, err = fetchDirectory(ctx, client, true); err != nil {
+				return nil, err
+			}
+			fresh = true
+		}
+		u, err := resolveUser(d, v)
+		if err != nil {
+			return nil, err
+		}
+		logging.Debug("user resolved", "query", v, "id", u.ID)
+		ids = append(ids, u.ID)
+	}
+	return ids, nil
+}
+
+// resolveTeam finds the single team matching an ID or name
+func resolveTeam(d *directory.Directory, query string) (*kdrive.Team, error) {
+	matches := d.FindTeams(query)
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("unknown team '%s' (see 'ktools teams ls')", query)
+	case 1:
+		return &matches[0], nil
+	}
+	ids := make([]string, len(matches))
+	for i, t := range matches {
+		ids[i] = strconv.Itoa(t.ID)
+	}
+	return nil, fmt.Errorf("ambiguous team '%s': IDs %s", query, strings.Join(ids, ", "))
+}
+
+// formatDate formats a Unix time as a date, "-" when unset
+func formatDate(ts int64) string {
+	if ts == 0 {
+		return "-"
+	}
+	return time.Unix(ts, 0).Format("2006-01-02")
+}
+
+var usersCmd = &cobra.Command{
+	Use:   "users",
+	Short: "List drive users",
+	Long: `List the users of the drive, to find the IDs, emails or names accepted by --user.
+Users and teams are cached locally (directory_ttl, default 24h); --refresh fetches them again.`,
+}
+
+var usersLsCmd = &cobra.Command{
+	Use:   "ls",
+	Short: "List drive users",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		d, err := loadDirectory(cmd.Context(), newClient())
+		if err != nil {
+			return err
+		}
+
+		users := append([]kdrive.DriveUser(nil), d.Users...)
+		sort.Slice(users, func(i, j int) bool {
+			return strings.ToLower(users[i].DisplayName) < strings.ToLower(users[j].DisplayName)
+		})
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tNAME\tEMAIL\tROLE\tSTATUS\tLAST LOGIN")
+		for _, u := range users {
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\n",
+				u.ID, u.DisplayName, u.Email, u.Role, u.Status, formatDate(u.LastConnectionAt))
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		fmt.Printf("\nTotal: %d users\n", len(users))
+		return nil
+	},
+}
+
+var usersShowCmd = &cobra.Command{
+	Use:   "show <id_or_email_or_name>",
+	Short: "Show a drive user and their teams",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		d, err := loadDirectory(cmd.Context(), newClient())
+		if err != nil {
+			return err
+		}
+		u, err := resolveUser(d, args[0])
+		if err != nil {
+			return err
+		}
+
+		var teams []string
+		for _, t := range d.Teams {
+			for _, id := range t.UserIDs {
+				if id == u.ID {
+					teams = append(teams, t.Name)
+					break
+				}
+			}
+		}
+		sort.Strings(teams)
+		if len(teams) == 0 {
+			teams = []string{"-"}
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(w, "ID:\t%d\n", u.ID)
+		fmt.Fprintf(w, "Name:\t%s\n", u.DisplayName)
+		fmt.Fprintf(w, "Email:\t%s\n", u.Email)
+		fmt.Fprintf(w, "Role:\t%s\n", u.Role)
+		fmt.Fprintf(w, "Status:\t%s\n", u.Status)
+		fmt.Fprintf(w, "Created:\t%s\n", formatDate(u.CreatedAt))
+		fmt.Fprintf(w, "Last login:\t%s\n", formatDate(u.LastConnectionAt))
+		fmt.Fprintf(w, "Teams:\t%s\n", strings.Join(teams, ", "))
+		return w.Flush()
+	},
+}
+
+var teamsCmd = &cobra.Command{
+	Use:   "teams",
+	Short: "List drive teams",
+	Long:  "List the teams of the drive and their members. Teams are cached with users (see 'ktools users').",
+}
+
+var teamsLsCmd = &cobra.Command{
+	Use:   "ls",
+	Short: "List drive teams",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		d, err := loadDirectory(cmd.Context(), newClient())
+		if err != nil {
+			return err
+		}
+
+		teams := append([]kdrive.Team(nil), d.Teams...)
+		sort.Slice(teams, func(i, j int) bool {
+			return strings.ToLower(teams[i].Name) < strings.ToLower(teams[j].Name)
+		})
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tNAME\tMEMBERS")
+		for _, t := range teams {
+			fmt.Fprintf(w, "%d\t%s\t%d\n", t.ID, t.Name, len(t.UserIDs))
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		fmt.Printf("\nTotal: %d teams\n", len(teams))
+		return nil
+	},
+}
+
+var teamsShowCmd = &cobra.Command{
+	Use:   "show <id_or_name>",
+	Short: "Show the members of a team",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		d, err := loadDirectory(cmd.Context(), newClient())
+		if err != nil {
+			return err
+		}
+		t, err := resolveTeam(d, args[0])
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Team: %s (%d)\n\n", t.Name, t.ID)
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tNAME\tEMAIL\tROLE")
+		for _, id := range t.UserIDs {
+			if u := d.User(id); u != nil {
+				fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", u.ID, u.DisplayName, u.Email, u.Role)
+			} else {
+				fmt.Fprintf(w, "%d\t?\t?\t?\n", id)
+			}
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		fmt.Printf("\nTotal: %d members\n", len(t.UserIDs))
+		return nil
+	},
+}
+
+func init() {
+	for _, c := range []*cobra.Command{usersCmd, teamsCmd} {
+		c.PersistentFlags().BoolVar(&directoryRefresh, "refresh", false, "Fetch users and teams again instead of using the local cache")
+		rootCmd.AddCommand(c)
+	}
+	usersCmd.AddCommand(usersLsCmd, usersShowCmd)
+	teamsCmd.AddCommand(teamsLsCmd, teamsShowCmd)
+}
@@ -0,0 +1,328 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/gfaivre/ktools/internal/journal"
+	"github.com/gfaivre/ktools/pkg/kdrive"
+	"github.com/spf13/cobra"
+)
+
+var (
+	versionsOutput    string
+	versionsKeep      int
+	versionsOlderThan string
+	versionsDryRun    bool
+)
+
+// parseVersionID parses a version ID argument
+func parseVersionID(s string) (int, error) {
+	id, err := strconv.Atoi(s)
+	if err != nil || id <= 0 {
+		return 0, fmt.Errorf("invalid version ID '%s' (see 'ktools versions ls')", s)
+	}
+	return id, nil
+}
+
+// prunable returns the versions to delete: beyond the keep newest ones (when
+// keep > 0) and created before cutoff (when not zero). The newest version is
+// the current content and versions marked keep forever are never returned.
+func prunable(versions []kdrive.Version, keep int, cutoff time.Time) []kdrive.Version {
+	var prune []kdrive.Version
+	for i, v := range versions {
+		switch {
+		case i == 0, v.KeepForever:
+		case keep > 0 && i < keep:
+		case !cutoff.IsZero() && !time.Unix(v.CreatedAt, 0).Before(cutoff):
+		default:
+			prune = append(prune, v)
+		}
+	}
+	return prune
+}
+
+var versionsCmd = &cobra.Command{
+	Use:   "versions",
+	Short: "Manage file versions",
+	Long: `List, download, restore and prune the stored versions of files.
+Previous versions count in the drive quota but not in the file sizes reported by scan.`,
+}
+
+var versionsLsCmd = &cobra.Command{
+	Use:   "ls <file_path_or_id>",
+	Short: "List the versions of a file",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		client := newClient()
+
+		fileID, err := resolveFileID(ctx, client, args[0])
+		if err != nil {
+			return err
+		}
+		versions, err := client.ListVersions(ctx, fileID)
+		if err != nil {
+			return err
+		}
+		if len(versions) == 0 {
+			fmt.Println("No versions found")
+			return nil
+		}
+		users := userDirectory(ctx, client)
+
+		var stored int64
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tCREATED\tSIZE\tAUTHOR\tNOTE")
+		for i, v := range versions {
+			var notes []string
+			if i == 0 {
+				notes = append(notes, "current")
+			} else {
+				stored += v.Size
+			}
+			if v.KeepForever {
+				notes = append(notes, "keep forever")
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n", v.ID,
+				time.Unix(v.CreatedAt, 0).Format("2006-01-02 15:04"),
+				formatSize(v.Size), users.UserName(v.UserID), strings.Join(notes, ", "))
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		fmt.Printf("\nTotal: %d versions, %s in previous versions\n", len(versions), formatSize(stored))
+		return nil
+	},
+}
+
+var versionsGetCmd = &cobra.Command{
+	Use:   "get <file_path_or_id> <version_id>",
+	Short: "Download a version of a file",
+	Long:  "Download a version of a file, by default as '<name>.v<version_id><ext>' in the current directory ('-o -' for stdout).",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		client := newClient()
+
+		fileID, err := resolveFileID(ctx, client, args[0])
+		if err != nil {
+			return err
+		}
+		versionID, err := parseVersionID(args[1])
+		if err != nil {
+			return err
+		}
+
+		output := versionsOutput
+		if output == "" {
+			f, err := client.GetFile(ctx, fileID)
+			if err != nil {
+				return err
+			}
+			ext := path.Ext(f.Name)
+			output = fmt.Sprintf("%s.v%d%s", strings.TrimSuffix(f.Name, ext), versionID, ext)
+		}
+		if output == "-" {
+			_, err := client.DownloadVersion(ctx, fileID, versionID, os.Stdout)
+			return err
+		}
+
+		// Never overwrite an existing file: -o chooses another name
+		out, err := os.OpenFile(output, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if err != nil {
+			return fmt.Errorf("cannot create output file: %w", err)
+		}
+		n, err := client.DownloadVersion(ctx, fileID, versionID, out)
+		if cerr := out.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			os.Remove(output)
+			return err
+		}
+		fmt.Printf("Saved to: %s (%s)\n", output, formatSize(n))
+		return nil
+	},
+}
+
+var versionsRestoreCmd = &cobra.Command{
+	Use:   "restore <file_path_or_id> <version_id>",
+	Short: "Restore a version of a file",
+	Long:  "Make a version the current content of its file. The replaced content is kept as a new version.",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		client := newClient()
+
+		fileID, err := resolveFileID(ctx, client, args[0])
+		if err != nil {
+			return err
+		}
+		versionID, err := parseVersionID(args[1])
+		if err != nil {
+			return err
+		}
+		if err := client.RestoreVersion(ctx, fileID, versionID); err != nil {
+			return err
+		}
+		entry := newJournalEntry(cmd, args)
+		entry.Add(journal.Operation{Kind: journal.VersionRestore, Detail: fmt.Sprintf("version %d", versionID), FileIDs: []int{fileID}})
+		fmt.Printf("Version %d of file %d restored\n", versionID, fileID)
+		saveJournal(entry)
+		return nil
+	},
+}
+
+var versionsPruneCmd = &cobra.Command{
+	Use:   "prune <path_or_id_or_glob>...",
+	Short: "Delete old versions of the files below directories",
+	Long: `Delete the previous versions of every file in the targets and below them, keeping the --keep
+newest versions and/or the versions more recent than --older-than (both conditions apply when set).
+The current content and the versions marked keep forever are never deleted. Deleted versions cannot be restored.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		client := newClient()
+
+		if versionsKeep <= 0 && versionsOlderThan == "" {
+			return fmt.Errorf("nothing to prune: set --keep and/or --older-than")
+		}
+		var cutoff time.Time
+		if versionsOlderThan != "" {
+			days, err := parseAge(versionsOlderThan)
+			if err != nil {
+				return fmt.Errorf("invalid --older-than: %w", err)
+			}
+			cutoff = time.Now().AddDate(0, 0, -days)
+		}
+
+		raw, err := targetArgs(args)
+		if err != nil {
+			return err
+		}
+		if len(raw) == 0 {
+			return fmt.Errorf("no file given (argument, '-' or --from-file)")
+		}
+		targets, err := resolveTargets(ctx, client, raw)
+		if err != nil {
+			return err
+		}
+		cp, err := openCheckpoint(cmd, args)
+		if err != nil {
+			return err
+		}
+		// prune always applies below the targets
+		entries, err := collectFiles(ctx, client, targets, true, cp)
+		if err != nil {
+			return err
+		}
+		finishCheckpoint(cp)
+		if entries, err = applyTagFilter(entries, true); err != nil {
+			return err
+		}
+
+		var files []fileInfo
+		var ids []int
+		for _, e := range entries {
+			if e.Type != "dir" {
+				files = append(files, e)
+				ids = append(ids, e.ID)
+			}
+		}
+		if len(files) == 0 {
+			fmt.Println("No files found")
+			return nil
+		}
+
+		versions, err := client.FileVersions(ctx, ids, func(done, total int) {
+			fmt.Fprintf(os.Stderr, "\r\033[KListing versions: %d/%d", done, total)
+		})
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			if ctx.Err() != nil {
+				return err
+			}
+			fmt.Fprintf(os.Stderr, "Warning: some versions could not be listed, they are not pruned: %v\n", err)
+		}
+
+		entry := newJournalEntry(cmd, args)
+		defer saveJournal(entry)
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSIONS\tPRUNED\tSIZE\tID\tPATH")
+		var pruned, failed, prunedFiles int
+		var reclaimed int64
+		for _, f := range files {
+			prune := prunable(versions[f.ID], versionsKeep, cutoff)
+			if len(prune) == 0 {
+				continue
+			}
+
+			var size int64
+			deleted := 0
+			for _, v := range prune {
+				if !versionsDryRun {
+					if err := client.DeleteVersion(ctx, f.ID, v.ID); err != nil {
+						if ctx.Err() != nil {
+							return err
+						}
+						fmt.Fprintf(os.Stderr, "Warning: version %d of %s not deleted: %v\n", v.ID, f.DrivePath, err)
+						failed++
+						continue
+					}
+				}
+				size += v.Size
+				deleted++
+			}
+			if deleted > 0 {
+				prunedFiles++
+				if !versionsDryRun {
+					entry.Add(journal.Operation{Kind: journal.VersionDelete, FileIDs: []int{f.ID}})
+				}
+			}
+			pruned += deleted
+			reclaimed += size
+			fmt.Fprintf(w, "%d\t%d\t%s\t%d\t%s\n", len(versions[f.ID]), deleted, formatSize(size), f.ID, f.DrivePath)
+		}
+		if prunedFiles == 0 && failed == 0 {
+			fmt.Printf("No versions to prune among %d files\n", len(files))
+			return nil
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+
+		if versionsDryRun {
+			fmt.Printf("\nDry run: %d versions of %d files would be deleted, reclaiming %s\n", pruned, prunedFiles, formatSize(reclaimed))
+			return nil
+		}
+		fmt.Printf("\nDeleted %d versions of %d files, reclaimed %s\n", pruned, prunedFiles, formatSize(reclaimed))
+		if failed > 0 {
+			return fmt.Errorf("%d versions could not be deleted", failed)
+		}
+		return nil
+	},
+}
+
+func init() {
+	versionsGetCmd.Flags().StringVarP(&versionsOutput, "output", "o", "", "Output file path ('-' for stdout)")
+	versionsPruneCmd.Flags().IntVar(&versionsKeep, "keep", 0, "Keep the N newest versions of each file, current one included")
+	versionsPruneCmd.Flags().StringVar(&versionsOlderThan, "older-than", "", "Only delete versions older than this (e.g., 90d, 6m, 1y)")
+	versionsPruneCmd.Flags().BoolVar(&versionsDryRun, "dry-run", false, "Show what would be deleted")
+	versionsPruneCmd.Flags().BoolVar(&strict, "strict", false, "Fail if any directory cannot be listed")
+	versionsPruneCmd.Flags().BoolVar(&resume, "resume", false, "Resume an interrupted listing from its checkpoint")
+	// Subset of the tag filters: --older-than applies to versions here
+	versionsPruneCmd.Flags().StringSliceVar(&tagFilterOpts.Exts, "ext", nil, "Only files with these extensions (repeatable, e.g. --ext pdf,docx)")
+	versionsPruneCmd.Flags().StringVar(&tagFilterOpts.Name, "name", "", "Only files whose name matches this glob (e.g. '*invoice*')")
+	versionsPruneCmd.Flags().StringVar(&tagFilterOpts.MinSize, "min-size", "", "Only files at least this size (e.g. 500K, 10MB)")
+	versionsPruneCmd.Flags().StringArrayVar(&tagFilterOpts.Excludes, "exclude", nil, "Skip entries (and subtrees) whose relative path or name matches this glob (repeatable)")
+	addTargetFlags(versionsPruneCmd)
+
+	versionsCmd.AddCommand(versionsLsCmd, versionsGetCmd, versionsRestoreCmd, versionsPruneCmd)
+	rootCmd.AddCommand(versionsCmd)
+}
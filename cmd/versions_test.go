@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/gfaivre/ktools/pkg/kdrive"
+)
+
+func TestPrunable(t *testing.T) {
+	now := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	day := func(d int) int64 { return now.AddDate(0, 0, -d).Unix() }
+	// Newest first, as listed by the API
+	versions := []kdrive.Version{
+		{ID: 6, CreatedAt: day(0)},
+		{ID: 5, CreatedAt: day(10)},
+		{ID: 4, CreatedAt: day(40), KeepForever: true},
+		{ID: 3, CreatedAt: day(100)},
+		{ID: 2, CreatedAt: day(200)},
+		{ID: 1, CreatedAt: day(300)},
+	}
+	cutoff := now.AddDate(0, 0, -90)
+
+	tests := []struct {
+		name   string
+		keep   int
+		cutoff time.Time
+		want   []int
+	}{
+		{"keep 1", 1, time.Time{}, []int{5, 3, 2, 1}},
+		{"keep 3", 3, time.Time{}, []int{3, 2, 1}},
+		{"keep more than listed", 10, time.Time{}, nil},
+		{"older than", 0, cutoff, []int{3, 2, 1}},
+		{"keep and older than", 5, cutoff, []int{1}},
+		{"current version only", 0, now.AddDate(1, 0, 0), []int{5, 3, 2, 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []int
+			for _, v := range prunable(versions, tt.keep, tt.cutoff) {
+				got = append(got, v.ID)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("prunable = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if got := prunable(nil, 1, cutoff); len(got) != 0 {
+		t.Errorf("prunable(nil) = %v", got)
+	}
+}
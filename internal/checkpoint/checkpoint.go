@@ -0,0 +1,91 @@
+// Package checkpoint persists the progress of long-running commands so an
+// interrupted run can be resumed.
+package checkpoint
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/gfaivre/ktools/internal/config"
+	"github.com/gfaivre/ktools/pkg/kdrive"
+)
+
+// State is the persisted progress of a command run
+type State struct {
+	Command   string            `json:"command"`
+	DriveID   int               `json:"drive_id"`
+	UpdatedAt time.Time         `json:"updated_at"`
+	Walk      *kdrive.WalkState `json:"walk,omitempty"`
+	WalkDone  bool              `json:"walk_done"`
+	Done      []int             `json:"done,omitempty"` // IDs processed by completed batches
+
+	// Aggregate is the command-specific result of a streaming walk so far
+	Aggregate json.RawMessage `json:"aggregate,omitempty"`
+}
+
+// Checkpoint is the on-disk checkpoint of one command invocation
+type Checkpoint struct {
+	path  string
+	State State
+}
+
+// Open returns the checkpoint identified by drive and command line.
+// The same command with the same arguments maps to the same checkpoint.
+func Open(driveID int, command string) (*Checkpoint, error) {
+	dir, err := config.StateDir("checkpoints")
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha1.Sum([]byte(strconv.Itoa(driveID) + "\x00" + command))
+	return &Checkpoint{
+		path:  filepath.Join(dir, hex.EncodeToString(sum[:8])+".json"),
+		State: State{Command: command, DriveID: driveID},
+	}, nil
+}
+
+// Load reads the checkpoint from disk. It returns false if none exists.
+func (c *Checkpoint) Load() (bool, error) {
+	data, err := os.ReadFile(c.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("checkpoint read error: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &c.State); err != nil {
+		return false, fmt.Errorf("checkpoint parse error (%s): %w", c.path, err)
+	}
+	return true, nil
+}
+
+// Save writes the checkpoint atomically
+func (c *Checkpoint) Save() error {
+	c.State.UpdatedAt = time.Now()
+	data, err := json.Marshal(c.State)
+	if err != nil {
+		return fmt.Errorf("checkpoint encoding error: %w", err)
+	}
+
+	tmp := c.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("checkpoint write error: %w", err)
+	}
+	return os.Rename(tmp, c.path)
+}
+
+// Remove deletes the checkpoint, typically after a successful run
+func (c *Checkpoint) Remove() error {
+	if err := os.Remove(c.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
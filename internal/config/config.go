@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/spf13/viper"
 )
@@ -13,6 +14,44 @@ type Config struct {
 	AdminToken string `mapstructure:"admin_token"`
 	DriveID    int    `mapstructure:"drive_id"`
 	BaseURL    string `mapstructure:"base_url"`
+
+	// Throughput tuning
+	RateLimit    float64 `mapstructure:"rate_limit"`     // requests per second
+	RateBurst    int     `mapstructure:"rate_burst"`     // token bucket burst
+	MaxRateLimit float64 `mapstructure:"max_rate_limit"` // ceiling for adaptive rate
+	AdaptiveRate bool    `mapstructure:"adaptive_rate"`  // AIMD rate adjustment
+	Workers      int     `mapstructure:"workers"`        // concurrent listings/batches
+
+	// Retries of failed requests
+	MaxAttempts int           `mapstructure:"max_attempts"` // attempts per request, including the first
+	RetryBudget time.Duration `mapstructure:"retry_budget"` // total time spent retrying a request
+
+	// HTTP transport
+	ProxyURL        string `mapstructure:"proxy_url"`            // http(s)://[user:pass@]host:port (default: HTTPS_PROXY/HTTP_PROXY)
+	CAFile          string `mapstructure:"ca_file"`              // extra PEM CA bundle
+	ClientCert      string `mapstructure:"client_cert"`          // PEM client certificate (mutual TLS)
+	ClientKey       string `mapstructure:"client_key"`           // PEM client key
+	InsecureTLS     bool   `mapstructure:"insecure_skip_verify"` // skip TLS verification (test stand-ins only)
+	MaxIdleConns    int    `mapstructure:"max_idle_conns"`       // idle connections kept per host
+	MaxConnsPerHost int    `mapstructure:"max_conns_per_host"`   // 0 = unlimited
+	UserAgent       string `mapstructure:"user_agent"`
+
+	// Path resolution
+	PathCache          bool              `mapstructure:"path_cache"`           // persist the path -> ID index between runs
+	PathCacheTTL       time.Duration     `mapstructure:"path_cache_ttl"`       // drop cached paths older than this
+	CaseSensitivePaths bool              `mapstructure:"case_sensitive_paths"` // match path names exactly
+	Bookmarks          map[string]string `mapstructure:"bookmarks"`            // ~name shortcuts to a path or ID
+
+	// Sharing audit
+	InternalDomains []string `mapstructure:"internal_domains"` // email domains of the organization
+
+	// Users and teams
+	DirectoryTTL time.Duration `mapstructure:"directory_ttl"` // refetch the cached users and teams after this
+
+	// Debugging (set from command-line flags only)
+	Trace     bool   `mapstructure:"-"` // dump every HTTP exchange on stderr
+	RecordDir string `mapstructure:"-"` // save HTTP exchanges as fixtures
+	ReplayDir string `mapstructure:"-"` // serve HTTP exchanges from fixtures instead of the network
 }
 
 func Load() (*Config, error) {
@@ -31,6 +70,18 @@ func Load() (*Config, error) {
 
 	// Default values
 	viper.SetDefault("base_url", "https://api.infomaniak.com")
+	viper.SetDefault("rate_limit", 2)
+	viper.SetDefault("rate_burst", 5)
+	viper.SetDefault("max_rate_limit", 8)
+	viper.SetDefault("adaptive_rate", false)
+	viper.SetDefault("workers", 3)
+	viper.SetDefault("max_attempts", 4)
+	viper.SetDefault("retry_budget", "2m")
+	viper.SetDefault("max_idle_conns", 10)
+	viper.SetDefault("user_agent", "ktools")
+	viper.SetDefault("path_cache", true)
+	viper.SetDefault("path_cache_ttl", "24h")
+	viper.SetDefault("directory_ttl", "24h")
 
 	// Environment variables
 	viper.SetEnvPrefix("KTOOLS")
@@ -51,6 +102,20 @@ func Load() (*Config, error) {
 }
 
 func (c *Config) Validate() error {
+	if c.RecordDir != "" && c.ReplayDir != "" {
+		return fmt.Errorf("--record and --replay are mutually exclusive")
+	}
+	if c.ReplayDir != "" {
+		if _, err := os.Stat(c.ReplayDir); err != nil {
+			return fmt.Errorf("replay directory: %w", err)
+		}
+		if c.APIToken == "" {
+			c.APIToken = "replay" // recordings never contain the token
+		}
+	}
+	if (c.ClientCert == "") != (c.ClientKey == "") {
+		return fmt.Errorf("client_cert and client_key must be set together")
+	}
 	if c.APIToken == "" {
 		return fmt.Errorf("api_token required (config or KTOOLS_API_TOKEN)")
 	}
@@ -59,3 +124,18 @@ func (c *Config) Validate() error {
 	}
 	return nil
 }
+
+// StateDir returns the directory used to persist local state (journal, caches...),
+// ~/.config/ktools/<name>, creating it if needed.
+func StateDir(name string) (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("cannot find home directory: %w", err)
+	}
+
+	dir := filepath.Join(home, ".config", "ktools", name)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", fmt.Errorf("cannot create %s: %w", dir, err)
+	}
+	return dir, nil
+}
@@ -0,0 +1,148 @@
+// Package directory caches the users and teams of a drive between runs, so
+// user IDs can be displayed as names and looked up by email or name.
+package directory
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gfaivre/ktools/internal/config"
+	"github.com/gfaivre/ktools/pkg/kdrive"
+)
+
+// Directory is a snapshot of the users and teams of a drive
+type Directory struct {
+	DriveID   int                `json:"drive_id"`
+	FetchedAt time.Time          `json:"fetched_at"`
+	Users     []kdrive.DriveUser `json:"users"`
+	Teams     []kdrive.Team      `json:"teams"`
+}
+
+// User returns the user with the given ID, or nil
+func (d *Directory) User(id int) *kdrive.DriveUser {
+	if d == nil {
+		return nil
+	}
+	for i := range d.Users {
+		if d.Users[i].ID == id {
+			return &d.Users[i]
+		}
+	}
+	return nil
+}
+
+// UserName returns the display name of a user, or its ID when unknown ("-"
+// for no user)
+func (d *Directory) UserName(id int) string {
+	if id == 0 {
+		return "-"
+	}
+	if u := d.User(id); u != nil && u.DisplayName != "" {
+		return u.DisplayName
+	}
+	return strconv.Itoa(id)
+}
+
+// FindUsers returns the users whose ID, email or display name is query
+// (case-insensitive). An exact email match wins over name matches.
+func (d *Directory) FindUsers(query string) []kdrive.DriveUser {
+	if id, err := strconv.Atoi(query); err == nil {
+		if u := d.User(id); u != nil {
+			return []kdrive.DriveUser{*u}
+		}
+		return nil
+	}
+	for _, u := range d.Users {
+		if strings.EqualFold(u.Email, query) {
+			return []kdrive.DriveUser{u}
+		}
+	}
+	var matches []kdrive.DriveUser
+	for _, u := range d.Users {
+		if strings.EqualFold(u.DisplayName, query) {
+			matches = append(matches, u)
+		}
+	}
+	return matches
+}
+
+// FindTeams returns the teams whose ID or name is query (case-insensitive)
+func (d *Directory) FindTeams(query string) []kdrive.Team {
+	var matches []kdrive.Team
+	id, err := strconv.Atoi(query)
+	for _, t := range d.Teams {
+		if err == nil && t.ID == id || err != nil && strings.EqualFold(t.Name, query) {
+			matches = append(matches, t)
+		}
+	}
+	return matches
+}
+
+func cachePath(driveID int) (string, error) {
+	dir, err := config.StateDir("cache")
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "directory-"+strconv.Itoa(driveID)+".json"), nil
+}
+
+// Load returns the cached directory of a drive, or nil when there is none or
+// it is older than ttl (0 never expires)
+func Load(driveID int, ttl time.Duration) (*Directory, error) {
+	path, err := cachePath(driveID)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("directory cache read error: %w", err)
+	}
+
+	var d Directory
+	if err := json.Unmarshal(data, &d); err != nil {
+		return nil, fmt.Errorf("directory cache parse error (%s): %w", path, err)
+	}
+	if ttl > 0 && time.Since(d.FetchedAt) > ttl {
+		return nil, nil
+	}
+	return &d, nil
+}
+
+// Save writes the directory of a drive atomically
+func Save(d *Directory) error {
+	path, err := cachePath(d.DriveID)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(d)
+	if err != nil {
+		return fmt.Errorf("directory cache encoding error: %w", err)
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("directory cache write error: %w", err)
+	}
+	return os.Rename(tmp, path)
+}
+
+// Clear deletes the cached directory of a drive
+func Clear(driveID int) error {
+	path, err := cachePath(driveID)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,230 @@
+// Package journal records mutating operations so they can be inspected and reverted.
+package journal
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gfaivre/ktools/internal/config"
+)
+
+// Operation kinds
+const (
+	CategoryAdd    = "category_add"
+	CategoryRemove = "category_remove"
+
+	// Recorded for history only, these cannot be undone
+	CategoryCreate = "category_create"
+	CategoryUpdate = "category_update"
+	CategoryDelete = "category_delete"
+	ShareRevoke    = "share_revoke"
+	LinkCreate     = "link_create"
+	LinkUpdate     = "link_update"
+	LinkDelete     = "link_delete"
+	VersionRestore = "version_restore"
+	VersionDelete  = "version_delete"
+)
+
+// ErrNotFound is returned when a journal entry does not exist
+var ErrNotFound = errors.New("journal entry not found")
+
+// Operation is a single mutation applied to a set of files.
+// FileIDs only holds the files whose state actually changed.
+type Operation struct {
+	Kind         string `json:"kind"`
+	CategoryID   int    `json:"category_id,omitempty"`
+	CategoryName string `json:"category_name,omitempty"`
+	Detail       string `json:"detail,omitempty"`
+	FileIDs      []int  `json:"file_ids"`
+}
+
+// Undoable reports whether the operation can be reverted by undo
+func (o Operation) Undoable() bool {
+	return o.Kind == CategoryAdd || o.Kind == CategoryRemove
+}
+
+// Inverse returns the operation reverting o
+func (o Operation) Inverse() Operation {
+	inv := o
+	switch o.Kind {
+	case CategoryAdd:
+		inv.Kind = CategoryRemove
+	case CategoryRemove:
+		inv.Kind = CategoryAdd
+	}
+	inv.FileIDs = nil
+	return inv
+}
+
+// Entry is one journaled command run
+type Entry struct {
+	ID         string      `json:"id"`
+	CreatedAt  time.Time   `json:"created_at"`
+	DriveID    int         `json:"drive_id"`
+	Command    string      `json:"command"`
+	Operations []Operation `json:"operations"`
+	UndoOf     string      `json:"undo_of,omitempty"`
+	UndoneBy   string      `json:"undone_by,omitempty"`
+}
+
+// New creates an entry for the given drive and command line
+func New(driveID int, command string) *Entry {
+	now := time.Now()
+	suffix := make([]byte, 2)
+	rand.Read(suffix)
+	return &Entry{
+		ID:        now.Format("20060102-150405") + "-" + hex.EncodeToString(suffix),
+		CreatedAt: now,
+		DriveID:   driveID,
+		Command:   command,
+	}
+}
+
+// Record appends the changed file IDs of an operation, merging with an existing
+// operation of the same kind and category.
+func (e *Entry) Record(kind string, categoryID int, categoryName string, fileIDs []int) {
+	if len(fileIDs) == 0 {
+		return
+	}
+	for i := range e.Operations {
+		op := &e.Operations[i]
+		if op.Kind == kind && op.CategoryID == categoryID {
+			op.FileIDs = append(op.FileIDs, fileIDs...)
+			return
+		}
+	}
+	e.Operations = append(e.Operations, Operation{
+		Kind:         kind,
+		CategoryID:   categoryID,
+		CategoryName: categoryName,
+		FileIDs:      append([]int(nil), fileIDs...),
+	})
+}
+
+// Add appends an operation recorded for history only, merging its file IDs
+// with an existing operation of the same kind, category and detail.
+func (e *Entry) Add(op Operation) {
+	for i := range e.Operations {
+		o := &e.Operations[i]
+		if o.Kind == op.Kind && o.CategoryID == op.CategoryID && o.Detail == op.Detail {
+			o.FileIDs = append(o.FileIDs, op.FileIDs...)
+			return
+		}
+	}
+	op.FileIDs = append([]int(nil), op.FileIDs...)
+	e.Operations = append(e.Operations, op)
+}
+
+// Undoable reports whether every operation of the entry can be reverted
+func (e *Entry) Undoable() bool {
+	for _, op := range e.Operations {
+		if !op.Undoable() {
+			return false
+		}
+	}
+	return true
+}
+
+// Changed returns the total number of changes: files whose state changed, or
+// one per operation not bound to files (e.g. a category creation)
+func (e *Entry) Changed() int {
+	n := 0
+	for _, op := range e.Operations {
+		n += max(len(op.FileIDs), 1)
+	}
+	return n
+}
+
+func dir() (string, error) {
+	return config.StateDir("journal")
+}
+
+// Save writes the entry to the journal directory. Entries without changes are not saved.
+func Save(e *Entry) error {
+	if e.Changed() == 0 && e.UndoOf == "" {
+		return nil
+	}
+	d, err := dir()
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(e, "", "  ")
+	if err != nil {
+		return fmt.Errorf("journal encoding error: %w", err)
+	}
+
+	tmp := filepath.Join(d, e.ID+".json.tmp")
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("journal write error: %w", err)
+	}
+	return os.Rename(tmp, filepath.Join(d, e.ID+".json"))
+}
+
+// Load reads an entry by ID
+func Load(id string) (*Entry, error) {
+	d, err := dir()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(filepath.Join(d, filepath.Base(id)+".json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("journal read error: %w", err)
+	}
+
+	var e Entry
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil, fmt.Errorf("journal parse error (%s): %w", id, err)
+	}
+	return &e, nil
+}
+
+// List returns all entries for a drive, most recent first. Unreadable entries
+// are skipped: the others are returned with an error naming them.
+func List(driveID int) ([]*Entry, error) {
+	d, err := dir()
+	if err != nil {
+		return nil, err
+	}
+
+	names, err := os.ReadDir(d)
+	if err != nil {
+		return nil, fmt.Errorf("journal read error: %w", err)
+	}
+
+	var entries []*Entry
+	var skipped []string
+	for _, n := range names {
+		if n.IsDir() || !strings.HasSuffix(n.Name(), ".json") {
+			continue
+		}
+		e, err := Load(strings.TrimSuffix(n.Name(), ".json"))
+		if err != nil {
+			skipped = append(skipped, n.Name())
+			continue
+		}
+		if e.DriveID == driveID {
+			entries = append(entries, e)
+		}
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].CreatedAt.After(entries[j].CreatedAt)
+	})
+	if len(skipped) > 0 {
+		return entries, fmt.Errorf("journal parse error (%s): skipped entries %s", d, strings.Join(skipped, ", "))
+	}
+	return entries, nil
+}
@@ -0,0 +1,103 @@
+// Package pathcache persists the path index of a drive between runs so paths
+// resolve without listing every directory again.
+package pathcache
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/gfaivre/ktools/internal/config"
+	"github.com/gfaivre/ktools/pkg/kdrive"
+)
+
+// file is the on-disk format of the cache
+type file struct {
+	DriveID int                `json:"drive_id"`
+	Entries []kdrive.PathEntry `json:"entries"`
+}
+
+func cachePath(driveID int) (string, error) {
+	dir, err := config.StateDir("cache")
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "paths-"+strconv.Itoa(driveID)+".json"), nil
+}
+
+// Load returns the cached path index of a drive, without the entries older
+// than ttl (0 keeps them all). A missing cache gives an empty index.
+func Load(driveID int, ttl time.Duration) (*kdrive.PathIndex, error) {
+	x := kdrive.NewPathIndex()
+
+	path, err := cachePath(driveID)
+	if err != nil {
+		return x, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return x, nil
+	}
+	if err != nil {
+		return x, fmt.Errorf("path cache read error: %w", err)
+	}
+
+	var f file
+	if err := json.Unmarshal(data, &f); err != nil {
+		return x, fmt.Errorf("path cache parse error (%s): %w", path, err)
+	}
+
+	oldest := int64(0)
+	if ttl > 0 {
+		oldest = time.Now().Add(-ttl).Unix()
+	}
+	for _, e := range f.Entries {
+		if e.IndexedAt >= oldest {
+			x.AddEntry(e)
+		}
+	}
+	x.MarkClean()
+	return x, nil
+}
+
+// Save writes the path index of a drive atomically, if it changed since Load
+func Save(driveID int, x *kdrive.PathIndex) error {
+	if !x.Dirty() {
+		return nil
+	}
+
+	path, err := cachePath(driveID)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(file{DriveID: driveID, Entries: x.Entries()})
+	if err != nil {
+		return fmt.Errorf("path cache encoding error: %w", err)
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("path cache write error: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return err
+	}
+	x.MarkClean()
+	return nil
+}
+
+// Clear deletes the cached path index of a drive
+func Clear(driveID int) error {
+	path, err := cachePath(driveID)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
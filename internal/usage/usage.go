@@ -0,0 +1,159 @@
+// Package usage keeps a history of drive usage samples, one per 'drive info'
+// run, to show growth and project when the quota will be exhausted.
+package usage
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gfaivre/ktools/internal/config"
+)
+
+// Sample is the usage of a drive at a point in time
+type Sample struct {
+	Time     time.Time     `json:"time"`
+	Quota    int64         `json:"quota"` // 0 = unlimited
+	Used     int64         `json:"used"`
+	Trash    int64         `json:"trash"`
+	Versions int64         `json:"versions"`
+	Users    map[int]int64 `json:"users,omitempty"` // user ID -> used size
+}
+
+func historyPath(driveID int) (string, error) {
+	dir, err := config.StateDir("usage")
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "drive-"+strconv.Itoa(driveID)+".jsonl"), nil
+}
+
+// Load returns the samples of a drive, oldest first. A missing history is empty.
+// Lines that cannot be parsed (e.g. a write cut short) are skipped: the other
+// samples are returned along with an error listing the skipped lines.
+func Load(driveID int) ([]Sample, error) {
+	path, err := historyPath(driveID)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("usage history read error: %w", err)
+	}
+	defer f.Close()
+
+	var samples []Sample
+	var skipped []string
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		var s Sample
+		if err := json.Unmarshal(scanner.Bytes(), &s); err != nil {
+			skipped = append(skipped, strconv.Itoa(line))
+			continue
+		}
+		samples = append(samples, s)
+	}
+	if err := scanner.Err(); err != nil {
+		return samples, fmt.Errorf("usage history read error: %w", err)
+	}
+	if len(skipped) > 0 {
+		return samples, fmt.Errorf("usage history parse error (%s): skipped lines %s", path, strings.Join(skipped, ", "))
+	}
+	return samples, nil
+}
+
+// Append adds a sample to the history of a drive
+func Append(driveID int, s Sample) error {
+	path, err := historyPath(driveID)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(s)
+	if err != nil {
+		return fmt.Errorf("usage sample encoding error: %w", err)
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("usage history write error: %w", err)
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return fmt.Errorf("usage history write error: %w", err)
+	}
+	return f.Close()
+}
+
+// Baseline returns the sample to compare with for growth since a date: the
+// latest sample taken at or before since, else the oldest one (nil if none)
+func Baseline(samples []Sample, since time.Time) *Sample {
+	if len(samples) == 0 {
+		return nil
+	}
+	base := &samples[0]
+	for i := range samples {
+		if samples[i].Time.After(since) {
+			break
+		}
+		base = &samples[i]
+	}
+	return base
+}
+
+// DailyGrowth returns the growth of the used size in bytes per day, fitted
+// by least squares on the samples taken since a date. ok is false with fewer
+// than two samples or when they span less than an hour.
+func DailyGrowth(samples []Sample, since time.Time) (perDay float64, ok bool) {
+	var xs, ys []float64
+	for _, s := range samples {
+		if s.Time.Before(since) {
+			continue
+		}
+		xs = append(xs, s.Time.Sub(since).Hours()/24)
+		ys = append(ys, float64(s.Used))
+	}
+	if len(xs) < 2 || (xs[len(xs)-1]-xs[0])*24 < 1 {
+		return 0, false
+	}
+
+	var mx, my float64
+	for i := range xs {
+		mx += xs[i]
+		my += ys[i]
+	}
+	n := float64(len(xs))
+	mx, my = mx/n, my/n
+
+	var num, den float64
+	for i := range xs {
+		num += (xs[i] - mx) * (ys[i] - my)
+		den += (xs[i] - mx) * (xs[i] - mx)
+	}
+	if den == 0 {
+		return 0, false
+	}
+	return num / den, true
+}
+
+// Exhaustion projects when the quota will be full at the given daily growth.
+// ok is false for an unlimited quota or a usage that is not growing.
+func Exhaustion(s Sample, perDay float64) (at time.Time, ok bool) {
+	if s.Quota <= 0 || perDay <= 0 {
+		return time.Time{}, false
+	}
+	days := float64(s.Quota-s.Used) / perDay
+	if days < 0 {
+		days = 0
+	}
+	return s.Time.Add(time.Duration(days * 24 * float64(time.Hour))), true
+}
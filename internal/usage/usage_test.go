@@ -0,0 +1,58 @@
+package usage
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestDailyGrowth(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	at := func(days float64, used int64) Sample {
+		return Sample{Time: start.Add(time.Duration(days * 24 * float64(time.Hour))), Used: used}
+	}
+
+	tests := []struct {
+		name    string
+		samples []Sample
+		since   time.Time
+		perDay  float64
+		ok      bool
+	}{
+		{"no samples", nil, start, 0, false},
+		{"single sample", []Sample{at(0, 100)}, start, 0, false},
+		{"less than an hour", []Sample{at(0, 100), at(0.02, 200)}, start, 0, false},
+		{"linear growth", []Sample{at(0, 1000), at(1, 1100), at(2, 1200), at(3, 1300)}, start, 100, true},
+		{"shrinking", []Sample{at(0, 1000), at(2, 800)}, start, -100, true},
+		{"flat", []Sample{at(0, 500), at(1, 500), at(5, 500)}, start, 0, true},
+		{"least squares", []Sample{at(0, 0), at(1, 100), at(2, 300)}, start, 150, true},
+		{"older samples ignored", []Sample{at(0, 0), at(10, 5000), at(11, 5100)}, start.AddDate(0, 0, 5), 100, true},
+		{"too few recent samples", []Sample{at(0, 0), at(1, 100), at(10, 5000)}, start.AddDate(0, 0, 5), 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			perDay, ok := DailyGrowth(tt.samples, tt.since)
+			if ok != tt.ok || math.Abs(perDay-tt.perDay) > 1e-6 {
+				t.Errorf("DailyGrowth = %v, %v, want %v, %v", perDay, ok, tt.perDay, tt.ok)
+			}
+		})
+	}
+}
+
+func TestExhaustion(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := Sample{Time: now, Quota: 1000, Used: 400}
+
+	if at, ok := Exhaustion(s, 100); !ok || !at.Equal(now.AddDate(0, 0, 6)) {
+		t.Errorf("Exhaustion = %v, %v, want %v", at, ok, now.AddDate(0, 0, 6))
+	}
+	if _, ok := Exhaustion(s, 0); ok {
+		t.Error("Exhaustion without growth is set")
+	}
+	if _, ok := Exhaustion(Sample{Time: now, Used: 400}, 100); ok {
+		t.Error("Exhaustion with an unlimited quota is set")
+	}
+	if at, ok := Exhaustion(Sample{Time: now, Quota: 100, Used: 200}, 10); !ok || !at.Equal(now) {
+		t.Errorf("Exhaustion over quota = %v, %v, want now", at, ok)
+	}
+}
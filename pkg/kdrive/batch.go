@@ -0,0 +1,227 @@
+package kdrive
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+)
+
+// BatchOptions controls how category updates are split and sent
+type BatchOptions struct {
+	BatchSize   int // IDs per request (default 50)
+	Concurrency int // concurrent requests, still paced by the rate limiter (default: configured workers)
+
+	// OnResult is called once per file ID as results come in (from a single goroutine).
+	// err is non-nil when the ID could not be updated.
+	OnResult func(id int, changed bool, err error)
+}
+
+// BatchFailure is a file ID that could not be updated
+type BatchFailure struct {
+	ID  int
+	Err error
+}
+
+// BatchResult summarizes a batched category update
+type BatchResult struct {
+	Changed   []int // IDs whose state changed
+	Unchanged []int // IDs already in the requested state
+	Failed    []BatchFailure
+}
+
+// AddCategoryToFilesBatched adds a category to any number of files in concurrent batches
+func (c *Client) AddCategoryToFilesBatched(ctx context.Context, categoryID int, fileIDs []int, opts BatchOptions) (*BatchResult, error) {
+	return c.runCategoryBatches(ctx, http.MethodPost, categoryID, fileIDs, opts)
+}
+
+// RemoveCategoryFromFilesBatched removes a category from any number of files in concurrent batches
+func (c *Client) RemoveCategoryFromFilesBatched(ctx context.Context, categoryID int, fileIDs []int, opts BatchOptions) (*BatchResult, error) {
+	return c.runCategoryBatches(ctx, http.MethodDelete, categoryID, fileIDs, opts)
+}
+
+type batchOutcome struct {
+	ids     []int
+	results []CategoryResult
+	err     error
+}
+
+// runCategoryBatches sends batches concurrently. Batches failing because of a
+// single ID (404, or a 400/422 naming one of the IDs) are retried one ID at a
+// time to isolate the faulty IDs; other batches rejected as too large (413, or
+// a 422 naming none of the IDs) shrink the batch size for the rest of the run. Errors that no ID can fix
+// (unauthorized, forbidden, still rate limited) stop the run: the IDs not sent
+// yet are reported as failed with that error. Per-ID failures are reported in
+// the result; the returned error is only set on context cancellation.
+func (c *Client) runCategoryBatches(ctx context.Context, method string, categoryID int, fileIDs []int, opts BatchOptions) (*BatchResult, error) {
+	size := opts.BatchSize
+	if size <= 0 {
+		size = 50
+	}
+	workers := opts.Concurrency
+	if workers <= 0 {
+		workers = c.workers
+	}
+
+	res := &BatchResult{}
+	report := func(id int, changed bool, err error) {
+		if opts.OnResult != nil {
+			opts.OnResult(id, changed, err)
+		}
+	}
+
+	jobs := make(chan []int)
+	outcomes := make(chan batchOutcome)
+
+	for i := 0; i < workers; i++ {
+		go func() {
+			for ids := range jobs {
+				results, err := c.modifyCategory(ctx, method, categoryID, ids)
+				select {
+				case outcomes <- batchOutcome{ids: ids, results: results, err: err}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+	defer close(jobs)
+
+	queue := chunkIDs(fileIDs, size)
+	inFlight := 0
+
+	fail := func(ids []int, err error) {
+		for _, id := range ids {
+			res.Failed = append(res.Failed, BatchFailure{ID: id, Err: err})
+			report(id, false, err)
+		}
+	}
+
+	for len(queue) > 0 || inFlight > 0 {
+		// Only offer a job when one is queued (a nil channel blocks forever)
+		var send chan []int
+		var next []int
+		if len(queue) > 0 {
+			send = jobs
+			next = queue[0]
+		}
+
+		select {
+		case <-ctx.Done():
+			return res, ctx.Err()
+
+		case send <- next:
+			queue = queue[1:]
+			inFlight++
+
+		case o := <-outcomes:
+			inFlight--
+
+			if o.err == nil {
+				seen := make(map[int]bool, len(o.results))
+				for _, r := range o.results {
+					seen[r.ID] = true
+					if r.Result {
+						res.Changed = append(res.Changed, r.ID)
+					} else {
+						res.Unchanged = append(res.Unchanged, r.ID)
+					}
+					report(r.ID, r.Result, nil)
+				}
+				for _, id := range o.ids {
+					if !seen[id] {
+						err := fmt.Errorf("no result returned")
+						res.Failed = append(res.Failed, BatchFailure{ID: id, Err: err})
+						report(id, false, err)
+					}
+				}
+				continue
+			}
+
+			if ctx.Err() != nil {
+				return res, ctx.Err()
+			}
+
+			status := StatusCode(o.err)
+			switch {
+			case isFatalBatchError(o.err):
+				// Every other request would fail the same way: give up on the queued IDs
+				c.logger.Debug("batch failed, stopping", "err", o.err, "remaining", len(flattenIDs(queue)))
+				fail(o.ids, o.err)
+				fail(flattenIDs(queue), fmt.Errorf("not sent: %w", o.err))
+				queue = nil
+
+			case len(o.ids) > 1 && isSingleIDError(o.err, o.ids):
+				// Retry each ID on its own to isolate the faulty ones
+				c.logger.Debug("batch failed, retrying IDs individually", "count", len(o.ids), "err", o.err)
+				queue = append(chunkIDs(o.ids, 1), queue...)
+
+			case (status == http.StatusRequestEntityTooLarge || status == http.StatusUnprocessableEntity) && len(o.ids) > 1:
+				// Batch too large: shrink for the rest of the run and re-split everything queued
+				if half := len(o.ids) / 2; half < size {
+					size = half
+				}
+				c.logger.Debug("batch rejected, shrinking batch size", "status", status, "size", size)
+				pending := append(append([]int(nil), o.ids...), flattenIDs(queue)...)
+				queue = chunkIDs(pending, size)
+
+			default:
+				fail(o.ids, o.err)
+			}
+		}
+	}
+
+	return res, nil
+}
+
+// isFatalBatchError reports whether an error applies to the whole run rather
+// than to the IDs of a batch
+func isFatalBatchError(err error) bool {
+	return errors.Is(err, ErrUnauthorized) || errors.Is(err, ErrForbidden) || errors.Is(err, ErrRateLimited)
+}
+
+var numberRe = regexp.MustCompile(`\d+`)
+
+// isSingleIDError reports whether a batch error may come from one of its IDs:
+// a 404, or a 400/422 whose message names one of the IDs
+func isSingleIDError(err error, ids []int) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	switch apiErr.StatusCode {
+	case http.StatusNotFound:
+		return true
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		inBatch := make(map[int]bool, len(ids))
+		for _, id := range ids {
+			inBatch[id] = true
+		}
+		for _, n := range numberRe.FindAllString(apiErr.Description+" "+apiErr.Body, -1) {
+			if id, err := strconv.Atoi(n); err == nil && inBatch[id] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// chunkIDs splits ids into slices of at most size elements
+func chunkIDs(ids []int, size int) [][]int {
+	var chunks [][]int
+	for i := 0; i < len(ids); i += size {
+		end := min(i+size, len(ids))
+		chunks = append(chunks, ids[i:end])
+	}
+	return chunks
+}
+
+func flattenIDs(chunks [][]int) []int {
+	var ids []int
+	for _, c := range chunks {
+		ids = append(ids, c...)
+	}
+	return ids
+}
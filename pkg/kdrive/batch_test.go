@@ -0,0 +1,121 @@
+package kdrive
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestIsSingleIDError(t *testing.T) {
+	ids := []int{101, 102, 103}
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"404", &APIError{StatusCode: http.StatusNotFound}, true},
+		{"400 naming an ID", &APIError{StatusCode: http.StatusBadRequest, Description: "file 102 is locked"}, true},
+		{"422 naming an ID in the body", &APIError{StatusCode: http.StatusUnprocessableEntity, Body: `{"error":{"context":{"file_id":103}}}`}, true},
+		{"422 naming no ID", &APIError{StatusCode: http.StatusUnprocessableEntity, Description: "too many files"}, false},
+		{"400 naming another number", &APIError{StatusCode: http.StatusBadRequest, Description: "at most 50 files"}, false},
+		{"413", &APIError{StatusCode: http.StatusRequestEntityTooLarge}, false},
+		{"500", &APIError{StatusCode: http.StatusInternalServerError, Description: "file 101"}, false},
+		{"not an API error", errors.New("file 101"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isSingleIDError(tt.err, ids); got != tt.want {
+				t.Errorf("isSingleIDError = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// replayClient returns a client serving the fixtures of testdata/dir
+func replayClient(t *testing.T, dir string) *Client {
+	t.Helper()
+	rt, err := NewReplayTransport("testdata/" + dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return New("token", 1, WithTransport(rt), WithRateLimiter(Unlimited()), WithWorkers(1))
+}
+
+func failedIDs(res *BatchResult) []int {
+	var ids []int
+	for _, f := range res.Failed {
+		ids = append(ids, f.ID)
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+func TestCategoryBatches(t *testing.T) {
+	ctx := context.Background()
+	c := replayClient(t, "batch")
+
+	tests := []struct {
+		name      string
+		remove    bool
+		ids       []int
+		size      int
+		changed   []int
+		unchanged []int
+		failed    []int
+	}{
+		// 413: the batch size is halved for the rest of the run
+		{"shrink on 413", false, []int{1, 2, 3, 4}, 4, []int{1, 3, 4}, []int{2}, nil},
+		// 422 naming none of the IDs: shrink as well
+		{"shrink on 422", true, []int{10, 11, 12, 13}, 4, []int{10, 11, 12, 13}, nil, nil},
+		// 404: each ID is retried alone to isolate the faulty one
+		{"isolate on 404", false, []int{5, 6, 7}, 3, []int{5, 7}, nil, []int{6}},
+		// 422 naming an ID: isolate, do not shrink
+		{"isolate on 422", false, []int{8, 9}, 2, []int{8}, nil, []int{9}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			run := c.AddCategoryToFilesBatched
+			if tt.remove {
+				run = c.RemoveCategoryFromFilesBatched
+			}
+			res, err := run(ctx, 7, tt.ids, BatchOptions{BatchSize: tt.size, Concurrency: 1})
+			if err != nil {
+				t.Fatal(err)
+			}
+			slices.Sort(res.Changed)
+			slices.Sort(res.Unchanged)
+			if !slices.Equal(res.Changed, tt.changed) {
+				t.Errorf("changed = %v, want %v", res.Changed, tt.changed)
+			}
+			if !slices.Equal(res.Unchanged, tt.unchanged) {
+				t.Errorf("unchanged = %v, want %v", res.Unchanged, tt.unchanged)
+			}
+			if got := failedIDs(res); !slices.Equal(got, tt.failed) {
+				t.Errorf("failed = %v, want %v (%v)", got, tt.failed, res.Failed)
+			}
+		})
+	}
+}
+
+func TestCategoryBatchesStopOnFatalError(t *testing.T) {
+	c := replayClient(t, "batch")
+
+	res, err := c.RemoveCategoryFromFilesBatched(context.Background(), 7, []int{20, 21, 22, 23}, BatchOptions{BatchSize: 2, Concurrency: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := failedIDs(res); !slices.Equal(got, []int{20, 21, 22, 23}) {
+		t.Fatalf("failed = %v, want every ID", got)
+	}
+	for _, f := range res.Failed {
+		if !errors.Is(f.Err, ErrUnauthorized) {
+			t.Errorf("ID %d: error %v, want ErrUnauthorized", f.ID, f.Err)
+		}
+		if sent := !strings.Contains(f.Err.Error(), "not sent"); sent != (f.ID < 22) {
+			t.Errorf("ID %d: error %v, sent = %v", f.ID, f.Err, sent)
+		}
+	}
+}
@@ -0,0 +1,840 @@
+package kdrive
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultBaseURL is the Infomaniak API endpoint used unless WithBaseURL is given
+const DefaultBaseURL = "https://api.infomaniak.com"
+
+// Client is a kDrive API client bound to one drive. It is safe for concurrent use.
+type Client struct {
+	httpClient *http.Client
+	baseURL    string
+	token      string
+	driveID    int
+	throttle   *RateLimiter
+	retry      retryPolicy
+	workers    int
+	userAgent  string
+	paths      *PathIndex
+	exactCase  bool
+	logger     *slog.Logger
+}
+
+// Option customizes a Client built by New
+type Option func(*Client)
+
+// WithHTTPClient uses the given HTTP client instead of the default one
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = hc
+	}
+}
+
+// WithTransport sends requests through the given round tripper
+// (see NewTransport, NewTraceTransport, NewRecordTransport, NewReplayTransport)
+func WithTransport(rt http.RoundTripper) Option {
+	return func(c *Client) {
+		c.httpClient = &http.Client{Timeout: c.httpClient.Timeout, Transport: rt}
+	}
+}
+
+// WithUserAgent sets the User-Agent header of every request
+func WithUserAgent(ua string) Option {
+	return func(c *Client) {
+		c.userAgent = ua
+	}
+}
+
+// WithBaseURL overrides the API base URL (default: DefaultBaseURL)
+func WithBaseURL(baseURL string) Option {
+	return func(c *Client) {
+		if baseURL != "" {
+			c.baseURL = strings.TrimSuffix(baseURL, "/")
+		}
+	}
+}
+
+// WithRateLimiter paces requests with l, which may be shared between clients
+// (default: a per-client limiter at a fixed 2 requests/s)
+func WithRateLimiter(l *RateLimiter) Option {
+	return func(c *Client) {
+		c.throttle = l
+	}
+}
+
+// WithRetry sets the number of attempts per request, including the first
+// (default 4), and the total time a request may spend waiting between
+// retries (default 2m).
+func WithRetry(maxAttempts int, budget time.Duration) Option {
+	return func(c *Client) {
+		c.retry = newRetryPolicy(maxAttempts, budget)
+	}
+}
+
+// WithPathIndex uses x as the path index of the client, e.g. to share it
+// between clients or to start from a persisted one
+func WithPathIndex(x *PathIndex) Option {
+	return func(c *Client) {
+		if x != nil {
+			c.paths = x
+		}
+	}
+}
+
+// WithCaseSensitivePaths makes path lookups match names exactly. By default
+// they are case-insensitive, preferring an exact-case match, and fail with
+// ErrAmbiguousPath when siblings differ only by case.
+func WithCaseSensitivePaths(exact bool) Option {
+	return func(c *Client) {
+		c.exactCase = exact
+	}
+}
+
+// WithLogger sends the debug logs of the client (requests, retries, rate
+// limiting) to l (default: discarded)
+func WithLogger(l *slog.Logger) Option {
+	return func(c *Client) {
+		if l != nil {
+			c.logger = l
+		}
+	}
+}
+
+// WithWorkers sets the number of concurrent requests of recursive listings
+// and batched updates (default 3)
+func WithWorkers(n int) Option {
+	return func(c *Client) {
+		if n > 0 {
+			c.workers = n
+		}
+	}
+}
+
+// New builds a client for driveID authenticated with an API token (scope: drive)
+func New(token string, driveID int, opts ...Option) *Client {
+	c := &Client{
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+		baseURL:    DefaultBaseURL,
+		token:      token,
+		driveID:    driveID,
+		throttle:   NewRateLimiter(2, 5, 8, false),
+		retry:      newRetryPolicy(0, 0),
+		workers:    3, // Keep low to avoid API connection limits
+		userAgent:  "ktools",
+		paths:      NewPathIndex(),
+		logger:     slog.New(slog.DiscardHandler),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// DriveID returns the drive the client is bound to
+func (c *Client) DriveID() int {
+	return c.driveID
+}
+
+func (c *Client) doRequest(ctx context.Context, method, path string, body io.Reader) ([]byte, error) {
+	// Buffer body to allow re-reads on retry
+	var bodyBytes []byte
+	if body != nil {
+		var err error
+		bodyBytes, err = io.ReadAll(body)
+		if err != nil {
+			return nil, fmt.Errorf("body read error: %w", err)
+		}
+	}
+
+	rawURL := c.baseURL + path
+	start := time.Now()
+
+	for attempt := 1; ; attempt++ {
+		c.logger.Debug("waiting for rate limiter", "method", method, "path", path)
+		if err := c.throttle.wait(ctx, c.logger); err != nil {
+			return nil, fmt.Errorf("rate limiter: %w", err)
+		}
+		c.logger.Debug("sending request", "method", method, "path", path, "attempt", attempt)
+
+		data, err := c.send(ctx, method, path, rawURL, bodyBytes)
+		if err == nil {
+			return data, nil
+		}
+
+		retry, delay := c.retry.decide(method, attempt, time.Since(start), err)
+		if !retry {
+			return nil, err
+		}
+		c.logger.Debug("retrying request", "method", method, "path", path, "delay", delay.Round(time.Millisecond), "err", err)
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// send performs a single HTTP exchange. HTTP error statuses are returned as *APIError.
+func (c *Client) send(ctx context.Context, method, path, rawURL string, bodyBytes []byte) ([]byte, error) {
+	var reqBody io.Reader
+	if bodyBytes != nil {
+		reqBody = bytes.NewReader(bodyBytes)
+	}
+
+	// Per-request timeout — more reliable than http.Client.Timeout for retries
+	reqCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(reqCtx, method, rawURL, reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("request creation error: %w", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", c.userAgent)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		if ctx.Err() == nil && reqCtx.Err() == context.DeadlineExceeded {
+			return nil, &timeoutError{method: method, path: path}
+		}
+		return nil, fmt.Errorf("HTTP request error: %w", err)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("response read error: %w", err)
+	}
+
+	c.throttle.observe(resp.Header, c.logger)
+	if resp.StatusCode == 429 || resp.StatusCode >= 500 {
+		c.throttle.backoff(c.logger)
+	} else {
+		c.throttle.success()
+	}
+
+	if resp.StatusCode >= 400 {
+		apiErr := newError(method, path, resp.StatusCode, data)
+		if retryAfter, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+			apiErr.RetryAfter = retryAfter
+			c.throttle.pause(retryAfter)
+		}
+		return nil, apiErr
+	}
+
+	return data, nil
+}
+
+// apiResponse is the envelope of API responses. The pagination fields are only
+// set by list endpoints: cursors on /3 endpoints, page counts on /2 ones.
+type apiResponse[T any] struct {
+	Result  string `json:"result"`
+	Data    T      `json:"data"`
+	Cursor  string `json:"cursor,omitempty"`
+	HasMore bool   `json:"has_more"`
+	Pages   int    `json:"pages"`
+}
+
+// send sends a request with an optional JSON body and decodes the API
+// response, whatever its result
+func send[T any](ctx context.Context, c *Client, method, path string, body any) (*apiResponse[T], error) {
+	var reqBody io.Reader
+	if body != nil {
+		jsonBody, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("JSON encoding error: %w", err)
+		}
+		reqBody = bytes.NewReader(jsonBody)
+	}
+
+	data, err := c.doRequest(ctx, method, path, reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp apiResponse[T]
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, fmt.Errorf("JSON parse error: %w", err)
+	}
+	return &resp, nil
+}
+
+// callPage is send for a successful response, returned with its pagination fields
+func callPage[T any](ctx context.Context, c *Client, method, path string, body any) (*apiResponse[T], error) {
+	resp, err := send[T](ctx, c, method, path, body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Result != "success" {
+		return nil, fmt.Errorf("API error: %s", resp.Result)
+	}
+	return resp, nil
+}
+
+// call sends a request with an optional JSON body and returns the data of
+// the successful API response
+func call[T any](ctx context.Context, c *Client, method, path string, body any) (T, error) {
+	resp, err := callPage[T](ctx, c, method, path, body)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return resp.Data, nil
+}
+
+type File struct {
+	ID             int    `json:"id"`
+	Name           string `json:"name"`
+	Type           string `json:"type"`
+	Status         string `json:"status"`
+	Visibility     string `json:"visibility"`
+	DriveID        int    `json:"drive_id"`
+	Depth          int    `json:"depth"`
+	Size           int64  `json:"size"`
+	CreatedBy      int    `json:"created_by"`
+	CreatedAt      int64  `json:"created_at"`
+	AddedAt        int64  `json:"added_at"`
+	LastModifiedAt int64  `json:"last_modified_at"`
+	LastModifiedBy int    `json:"last_modified_by"`
+	RevisedAt      int64  `json:"revised_at"`
+	UpdatedAt      int64  `json:"updated_at"`
+	ParentID       int    `json:"parent_id"`
+	Color          string `json:"color,omitempty"`
+	// Path is the drive path ("/Common documents/a.pdf"), set by recursive listings
+	Path string `json:"path,omitempty"`
+	// Categories is only populated by listings requested with categories
+	Categories []Category `json:"categories,omitempty"`
+	// ShareLink is only populated by listings requested with "sharelink"
+	// (nil when the file has no public link)
+	ShareLink *ShareLink `json:"sharelink,omitempty"`
+}
+
+func (c *Client) GetFile(ctx context.Context, fileID int) (*File, error) {
+	path := fmt.Sprintf("/3/drive/%d/files/%d", c.driveID, fileID)
+	file, err := call[File](ctx, c, http.MethodGet, path, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &file, nil
+}
+
+func (c *Client) ListFiles(ctx context.Context, fileID int) ([]File, error) {
+	return Collect(c.Files(ctx, fileID))
+}
+
+// ListFilesWithCategories lists direct children with their categories populated
+func (c *Client) ListFilesWithCategories(ctx context.Context, fileID int) ([]File, error) {
+	return Collect(c.FilesWithCategories(ctx, fileID))
+}
+
+// FindFileByPath searches for a file/directory by path from the root.
+// Paths known to the path index (with the exact case) are validated with one
+// GetFile per path level (name, parent and updated_at); otherwise the lookup
+// starts from the deepest indexed ancestor, validated the same way.
+// Case-insensitive segments are always listed so siblings differing only by
+// case are detected.
+func (c *Client) FindFileByPath(ctx context.Context, filePath string) (*File, error) {
+	filePath = CleanPath(filePath)
+
+	if id, ok := c.paths.ID(filePath, true); ok {
+		f, err := c.checkIndexedPath(ctx, id, filePath)
+		if err != nil {
+			return nil, err
+		}
+		if f != nil && c.validEntry(id, f, filePath) {
+			f.Path, _ = c.paths.Path(id)
+			return f, nil
+		}
+		c.logger.Debug("path index entry outdated", "path", filePath, "id", id)
+		c.paths.Remove(id)
+	}
+
+	f, err := c.resolvePath(ctx, filePath, true)
+	if errors.Is(err, errStaleAncestor) {
+		f, err = c.resolvePath(ctx, filePath, false)
+	}
+	return f, err
+}
+
+// errStaleAncestor reports an indexed ancestor that no longer exists or moved
+var errStaleAncestor = errors.New("indexed ancestor outdated")
+
+// checkIndexedPath checks that the indexed file id still has filePath: each
+// level up to the root must have the indexed name and parent. It returns the
+// file id, or nil when the index is outdated (e.g. /A renamed to /B and a new /A
+// created: the old ID of /A now has the path /B).
+func (c *Client) checkIndexedPath(ctx context.Context, id int, filePath string) (*File, error) {
+	var target *File
+	for {
+		if id == RootID && target != nil {
+			return target, nil
+		}
+		f, err := c.GetFile(ctx, id)
+		if errors.Is(err, ErrNotFound) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if target == nil {
+			target = f
+		}
+		if id == RootID {
+			return target, nil
+		}
+
+		i := strings.LastIndex(filePath, "/")
+		parentPath, name := CleanPath(filePath[:i]), filePath[i+1:]
+		parentID, ok := c.paths.ID(parentPath, true)
+		if f.Name != name || !ok || f.ParentID != parentID {
+			c.logger.Debug("path index entry moved", "path", filePath, "id", id, "name", f.Name, "parent", f.ParentID)
+			return nil, nil
+		}
+		id, filePath = parentID, parentPath
+	}
+}
+
+// validEntry checks a cached path against the current state of the file
+func (c *Client) validEntry(id int, f *File, filePath string) bool {
+	e, ok := c.paths.Entry(id)
+	if !ok {
+		return false
+	}
+	if e.UpdatedAt != 0 && e.UpdatedAt != f.UpdatedAt {
+		return false
+	}
+	if id == RootID {
+		return true
+	}
+	return f.Name == filePath[strings.LastIndex(filePath, "/")+1:]
+}
+
+// resolvePath lists directories segment by segment, starting from the deepest
+// indexed ancestor when useIndex is set. The ancestor is checked first, an
+// outdated one returns errStaleAncestor.
+func (c *Client) resolvePath(ctx context.Context, filePath string, useIndex bool) (*File, error) {
+	currentID, currentPath := RootID, "/"
+	parts := strings.Split(strings.Trim(filePath, "/"), "/")
+	rest := parts
+	fromIndex := false
+	if useIndex {
+		for i := len(parts) - 1; i > 0; i-- {
+			prefix := "/" + strings.Join(parts[:i], "/")
+			if id, ok := c.paths.ID(prefix, true); ok {
+				currentID, rest, fromIndex = id, parts[i:], true
+				currentPath, _ = c.paths.Path(id)
+				break
+			}
+		}
+	}
+	if fromIndex {
+		f, err := c.checkIndexedPath(ctx, currentID, currentPath)
+		if err != nil {
+			return nil, err
+		}
+		if f == nil {
+			c.paths.Remove(currentID)
+			return nil, errStaleAncestor
+		}
+	}
+
+	var current *File
+	for _, part := range rest {
+		if part == "" {
+			continue
+		}
+		f, err := c.findChild(ctx, currentID, part)
+		if err != nil {
+			if fromIndex && currentID != RootID && errors.Is(err, ErrNotFound) && !errors.Is(err, errNoSuchChild) {
+				c.paths.Remove(currentID)
+				return nil, errStaleAncestor
+			}
+			return nil, err
+		}
+		fromIndex = false
+		currentID = f.ID
+		currentPath = JoinPath(currentPath, f.Name)
+		f.Path = currentPath
+		c.paths.AddFile(f)
+		current = f
+	}
+
+	if current == nil {
+		f, err := c.GetFile(ctx, currentID)
+		if err != nil {
+			return nil, err
+		}
+		f.Path = currentPath
+		return f, nil
+	}
+	return current, nil
+}
+
+// errNoSuchChild distinguishes a missing path segment from a missing directory
+var errNoSuchChild = errors.New("no such entry")
+
+// ErrAmbiguousPath is returned when a case-insensitive path segment matches
+// several siblings and none of them exactly
+var ErrAmbiguousPath = errors.New("ambiguous path")
+
+// findChild returns the child of dirID named name. An exact-case match stops
+// the listing early; otherwise (unless case-sensitive) a unique
+// case-insensitive match is required.
+func (c *Client) findChild(ctx context.Context, dirID int, name string) (*File, error) {
+	var folded []File
+	for f, err := range c.Files(ctx, dirID) {
+		if err != nil {
+			return nil, err
+		}
+		if f.Name == name {
+			return &f, nil
+		}
+		if !c.exactCase && strings.EqualFold(f.Name, name) {
+			folded = append(folded, f)
+		}
+	}
+
+	switch len(folded) {
+	case 0:
+		return nil, fmt.Errorf("path %w: %s: %w", ErrNotFound, name, errNoSuchChild)
+	case 1:
+		return &folded[0], nil
+	default:
+		names := make([]string, len(folded))
+		for i, f := range folded {
+			names[i] = fmt.Sprintf("'%s' (%d)", f.Name, f.ID)
+		}
+		return nil, fmt.Errorf("%w: '%s' matches %s", ErrAmbiguousPath, name, strings.Join(names, ", "))
+	}
+}
+
+type ActivityUser struct {
+	ID          int    `json:"id"`
+	DisplayName string `json:"display_name"`
+	Email       string `json:"email"`
+}
+
+type Activity struct {
+	ID        int           `json:"id"`
+	CreatedAt int64         `json:"created_at"`
+	Action    string        `json:"action"`
+	FileID    int           `json:"file_id"`
+	UserID    int           `json:"user_id"`
+	NewPath   string        `json:"new_path"`
+	OldPath   string        `json:"old_path"`
+	User      *ActivityUser `json:"user"`
+}
+
+type ActivitiesOptions struct {
+	Cursor  string
+	Limit   int
+	Order   string // asc or desc
+	Actions []string
+	From    int64
+	Until   int64
+	Users   []int
+}
+
+func (c *Client) ListActivities(ctx context.Context, opts ActivitiesOptions) ([]Activity, string, bool, error) {
+	q := url.Values{}
+	q.Set("lang", "fr")
+	q.Set("with", "user")
+	if opts.Cursor != "" {
+		q.Set("cursor", opts.Cursor)
+	}
+	if opts.Limit > 0 {
+		q.Set("limit", strconv.Itoa(opts.Limit))
+	}
+	if opts.Order != "" {
+		q.Set("order", opts.Order)
+	}
+	for _, a := range opts.Actions {
+		q.Add("actions[]", a)
+	}
+	if opts.From > 0 {
+		q.Set("from", strconv.FormatInt(opts.From, 10))
+	}
+	if opts.Until > 0 {
+		q.Set("until", strconv.FormatInt(opts.Until, 10))
+	}
+	for _, u := range opts.Users {
+		q.Add("users[]", strconv.Itoa(u))
+	}
+
+	path := fmt.Sprintf("/3/drive/%d/activities?%s", c.driveID, q.Encode())
+	resp, err := callPage[[]Activity](ctx, c, http.MethodGet, path, nil)
+	if err != nil {
+		return nil, "", false, err
+	}
+	return resp.Data, resp.Cursor, resp.HasMore, nil
+}
+
+type ReportOptions struct {
+	Actions []string
+	Depth   string
+	Files   []int
+	From    int64
+	Until   int64
+	UserID  int
+	Users   []int
+	Terms   string
+}
+
+type ReportUser struct {
+	ID          int    `json:"id"`
+	DisplayName string `json:"display_name"`
+	Email       string `json:"email"`
+}
+
+type Report struct {
+	ID          int        `json:"id"`
+	Status      string     `json:"status"`
+	Size        string     `json:"size"`
+	DownloadURL string     `json:"download_url"`
+	GeneratedBy ReportUser `json:"generated_by"`
+	CreatedAt   int64      `json:"created_at"`
+	UpdatedAt   int64      `json:"updated_at"`
+}
+
+func (c *Client) CreateReport(ctx context.Context, opts ReportOptions) (int, error) {
+	q := url.Values{}
+	q.Set("lang", "en")
+	path := fmt.Sprintf("/2/drive/%d/activities/reports?%s", c.driveID, q.Encode())
+
+	body := map[string]any{}
+	if len(opts.Actions) > 0 {
+		body["actions"] = opts.Actions
+	}
+	if opts.Depth != "" {
+		body["depth"] = opts.Depth
+	}
+	if len(opts.Files) > 0 {
+		body["files"] = opts.Files
+	}
+	if opts.From > 0 {
+		body["from"] = opts.From
+	}
+	if opts.Until > 0 {
+		body["until"] = opts.Until
+	}
+	if opts.UserID > 0 {
+		body["user_id"] = opts.UserID
+	}
+	if len(opts.Users) > 0 {
+		body["users"] = opts.Users
+	}
+	if opts.Terms != "" {
+		body["terms"] = opts.Terms
+	}
+
+	resp, err := send[int](ctx, c, http.MethodPost, path, body)
+	if err != nil {
+		return 0, err
+	}
+	if resp.Result == "asynchronous" {
+		return 0, fmt.Errorf("report creation is asynchronous, ID not available in response")
+	}
+
+	if resp.Result != "success" {
+		return 0, fmt.Errorf("API error: %s", resp.Result)
+	}
+
+	return resp.Data, nil
+}
+
+func (c *Client) ReportExportURL(reportID int) string {
+	return fmt.Sprintf("https://kdrive.infomaniak.com/2/drive/%d/activities/reports/%d/export", c.driveID, reportID)
+}
+
+func (c *Client) DownloadReport(ctx context.Context, reportID int) ([]byte, error) {
+	exportURL := c.ReportExportURL(reportID)
+
+	reqCtx, cancel := context.WithTimeout(ctx, 120*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(reqCtx, "GET", exportURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("request creation error: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set("User-Agent", c.userAgent)
+
+	// Same transport as API calls, without the 30s client timeout (exports can be large)
+	downloadClient := &http.Client{Transport: c.httpClient.Transport}
+	resp, err := downloadClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("HTTP request error: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("download error (%d): %s", resp.StatusCode, string(body))
+	}
+
+	return io.ReadAll(resp.Body)
+}
+
+func (c *Client) GetReport(ctx context.Context, reportID int) (*Report, error) {
+	path := fmt.Sprintf("/2/drive/%d/activities/reports/%d", c.driveID, reportID)
+	resp, err := send[Report](ctx, c, http.MethodGet, path, nil)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Result == "asynchronous" {
+		return &Report{Status: "in_progress"}, nil
+	}
+
+	if resp.Result != "success" {
+		return nil, fmt.Errorf("API error: %s", resp.Result)
+	}
+
+	return &resp.Data, nil
+}
+
+func (c *Client) ListReports(ctx context.Context, page int) ([]Report, int, error) {
+	q := url.Values{}
+	if page > 1 {
+		q.Set("page", strconv.Itoa(page))
+	}
+	path := fmt.Sprintf("/2/drive/%d/activities/reports", c.driveID)
+	if len(q) > 0 {
+		path += "?" + q.Encode()
+	}
+
+	resp, err := send[[]Report](ctx, c, http.MethodGet, path, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	if resp.Result == "asynchronous" {
+		return nil, 0, nil
+	}
+
+	if resp.Result != "success" {
+		return nil, 0, fmt.Errorf("API error: %s", resp.Result)
+	}
+
+	return resp.Data, resp.Pages, nil
+}
+
+func (c *Client) DeleteReport(ctx context.Context, reportID int) error {
+	path := fmt.Sprintf("/2/drive/%d/activities/reports/%d", c.driveID, reportID)
+	resp, err := send[bool](ctx, c, http.MethodDelete, path, nil)
+	if err != nil {
+		return err
+	}
+	if resp.Result == "asynchronous" {
+		return nil
+	}
+
+	if resp.Result != "success" {
+		return fmt.Errorf("API error: %s", resp.Result)
+	}
+
+	return nil
+}
+
+type FileWithCategories struct {
+	ID         int        `json:"id"`
+	Name       string     `json:"name"`
+	Categories []Category `json:"categories"`
+}
+
+func (c *Client) GetFileCategories(ctx context.Context, fileID int) ([]Category, error) {
+	q := url.Values{}
+	q.Set("with", "file.categories")
+	path := fmt.Sprintf("/3/drive/%d/files/%d?%s", c.driveID, fileID, q.Encode())
+	file, err := call[FileWithCategories](ctx, c, http.MethodGet, path, nil)
+	if err != nil {
+		return nil, err
+	}
+	return file.Categories, nil
+}
+
+type Category struct {
+	ID           int    `json:"id"`
+	Name         string `json:"name"`
+	Color        string `json:"color"`
+	IsPredefined bool   `json:"is_predefined"`
+	CreatedBy    int    `json:"created_by"`
+	CreatedAt    int64  `json:"created_at"`
+}
+
+func (c *Client) ListCategories(ctx context.Context) ([]Category, error) {
+	path := fmt.Sprintf("/2/drive/%d/categories", c.driveID)
+	return call[[]Category](ctx, c, http.MethodGet, path, nil)
+}
+
+// categoryBody is the request body for category creation and update
+type categoryBody struct {
+	Name  string `json:"name,omitempty"`
+	Color string `json:"color,omitempty"`
+}
+
+func (c *Client) CreateCategory(ctx context.Context, name, color string) (*Category, error) {
+	path := fmt.Sprintf("/2/drive/%d/categories", c.driveID)
+	return c.writeCategory(ctx, http.MethodPost, path, categoryBody{Name: name, Color: color})
+}
+
+// UpdateCategory renames and/or recolors a category. Empty fields are left unchanged.
+func (c *Client) UpdateCategory(ctx context.Context, categoryID int, name, color string) (*Category, error) {
+	path := fmt.Sprintf("/2/drive/%d/categories/%d", c.driveID, categoryID)
+	return c.writeCategory(ctx, http.MethodPut, path, categoryBody{Name: name, Color: color})
+}
+
+func (c *Client) writeCategory(ctx context.Context, method, path string, body categoryBody) (*Category, error) {
+	category, err := call[Category](ctx, c, method, path, body)
+	if err != nil {
+		return nil, err
+	}
+	return &category, nil
+}
+
+func (c *Client) DeleteCategory(ctx context.Context, categoryID int) error {
+	path := fmt.Sprintf("/2/drive/%d/categories/%d", c.driveID, categoryID)
+	_, err := call[bool](ctx, c, http.MethodDelete, path, nil)
+	return err
+}
+
+// SearchFilesByCategory lists all files carrying the given category, drive-wide
+func (c *Client) SearchFilesByCategory(ctx context.Context, categoryID int) ([]File, error) {
+	return Collect(c.FilesByCategory(ctx, categoryID))
+}
+
+type CategoryResult struct {
+	ID     int  `json:"id"`
+	Result bool `json:"result"`
+}
+
+func (c *Client) modifyCategory(ctx context.Context, method string, categoryID int, fileIDs []int) ([]CategoryResult, error) {
+	path := fmt.Sprintf("/2/drive/%d/files/categories/%d", c.driveID, categoryID)
+
+	body := struct {
+		FileIDs []int `json:"file_ids"`
+	}{FileIDs: fileIDs}
+	return call[[]CategoryResult](ctx, c, method, path, body)
+}
+
+func (c *Client) AddCategoryToFiles(ctx context.Context, categoryID int, fileIDs []int) ([]CategoryResult, error) {
+	return c.modifyCategory(ctx, http.MethodPost, categoryID, fileIDs)
+}
+
+func (c *Client) RemoveCategoryFromFiles(ctx context.Context, categoryID int, fileIDs []int) ([]CategoryResult, error) {
+	return c.modifyCategory(ctx, http.MethodDelete, categoryID, fileIDs)
+}
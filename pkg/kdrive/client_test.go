@@ -0,0 +1,111 @@
+package kdrive
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"sync"
+	"testing"
+)
+
+// requestLog records the request URIs sent through a transport
+type requestLog struct {
+	next http.RoundTripper
+	mu   sync.Mutex
+	uris []string
+}
+
+func (l *requestLog) RoundTrip(req *http.Request) (*http.Response, error) {
+	l.mu.Lock()
+	l.uris = append(l.uris, req.URL.RequestURI())
+	l.mu.Unlock()
+	return l.next.RoundTrip(req)
+}
+
+func TestFindFileByPathIndexed(t *testing.T) {
+	tests := []struct {
+		name     string
+		indexed  map[int]string
+		path     string
+		id       int
+		err      error
+		requests []string
+	}{
+		{
+			name:    "ancestor renamed and recreated",
+			indexed: map[int]string{10: "/A"},
+			path:    "/A/report.pdf",
+			id:      21,
+			requests: []string{
+				"/3/drive/1/files/10",
+				"/3/drive/1/files/1/files",
+				"/3/drive/1/files/20/files",
+			},
+		},
+		{
+			name:    "target renamed and recreated",
+			indexed: map[int]string{10: "/A"},
+			path:    "/A",
+			id:      20,
+			requests: []string{
+				"/3/drive/1/files/10",
+				"/3/drive/1/files/1/files",
+			},
+		},
+		{
+			name:    "valid ancestor",
+			indexed: map[int]string{30: "/C"},
+			path:    "/C/d.txt",
+			id:      31,
+			requests: []string{
+				"/3/drive/1/files/30",
+				"/3/drive/1/files/30/files",
+			},
+		},
+		{
+			name:    "ancestor deleted",
+			indexed: map[int]string{40: "/E"},
+			path:    "/E/x.txt",
+			err:     ErrNotFound,
+			requests: []string{
+				"/3/drive/1/files/40",
+				"/3/drive/1/files/1/files",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rt, err := NewReplayTransport("testdata/paths")
+			if err != nil {
+				t.Fatal(err)
+			}
+			log := &requestLog{next: rt}
+			paths := NewPathIndex()
+			for id, p := range tt.indexed {
+				paths.Add(id, p)
+			}
+			c := New("token", 1, WithTransport(log), WithRateLimiter(Unlimited()), WithPathIndex(paths))
+
+			f, err := c.FindFileByPath(context.Background(), tt.path)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("error = %v, want %v", err, tt.err)
+				}
+			} else {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if f.ID != tt.id || f.Path != tt.path {
+					t.Errorf("found %d %q, want %d %q", f.ID, f.Path, tt.id, tt.path)
+				}
+				if id, _ := paths.ID(tt.path, true); id != tt.id {
+					t.Errorf("index maps %s to %d, want %d", tt.path, id, tt.id)
+				}
+			}
+			if !slices.Equal(log.uris, tt.requests) {
+				t.Errorf("requests = %v, want %v", log.uris, tt.requests)
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+// Package kdrive is a client for the Infomaniak kDrive API.
+//
+// A Client is bound to one drive and authenticated with an API token
+// (scope: drive):
+//
+//	client := kdrive.New(token, driveID, kdrive.WithWorkers(4))
+//	files, err := client.ListFiles(ctx, 1) // 1 is the drive root
+//
+// # Pagination
+//
+// Paginated endpoints are exposed as iterators (Files, FilesWithCategories,
+// FilesByCategory, Activities, Reports) that request pages lazily and stop
+// when the loop breaks. Errors are yielded as the last element:
+//
+//	for f, err := range client.Files(ctx, dirID) {
+//		if err != nil {
+//			return err
+//		}
+//		fmt.Println(f.Name)
+//	}
+//
+// Collect gathers an iterator into a slice; ListFiles and the other List
+// methods are shorthands for it.
+//
+// # Rate limiting and retries
+//
+// Requests are paced by a RateLimiter (a fixed 2 requests/s by default, or
+// adaptive). Clients created with the same WithRateLimiter share its budget.
+// Retry-After and rate limit quota headers pause every request of the limiter.
+//
+// Failed requests are retried with jittered exponential backoff (WithRetry):
+// 429 responses always, 502/503/504 responses and transient network errors
+// only for idempotent methods (GET, PUT, DELETE), so a POST is never sent twice.
+//
+// # Errors
+//
+// HTTP error statuses are returned as *APIError, carrying the status, the
+// endpoint and the API error code/description. They match ErrUnauthorized,
+// ErrForbidden, ErrNotFound, ErrRateLimited and ErrConflict with errors.Is:
+//
+//	if errors.Is(err, kdrive.ErrNotFound) { ... }
+//
+// # Recursive listings and batches
+//
+// Walk streams a tree to a callback as directories are listed by concurrent
+// workers, with depth limits, subtree pruning and breadth- or depth-first
+// ordering (WalkOptions). ListFilesRecursiveWithOptions collects the same walk
+// into a slice. Failing directories are retried; a *PartialError is returned
+// when some of them cannot be listed. The WalkState passed to
+// WalkOptions.Checkpoint can be saved and passed back to resume a walk.
+//
+// AddCategoryToFilesBatched and RemoveCategoryFromFilesBatched split large
+// updates into concurrent batches, isolating the IDs the API rejects.
+//
+// # Testing
+//
+// NewRecordTransport saves HTTP exchanges as JSON fixtures and
+// NewReplayTransport serves them back without network:
+//
+//	rt, err := kdrive.NewReplayTransport("testdata/ls")
+//	client := kdrive.New("", 42, kdrive.WithTransport(rt), kdrive.WithRateLimiter(kdrive.Unlimited()))
+package kdrive
@@ -0,0 +1,55 @@
+package kdrive
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// Drive is the description and quota of a drive
+type Drive struct {
+	ID        int       `json:"id"`
+	Name      string    `json:"name"`
+	AccountID int       `json:"account_id"`
+	Size      int64     `json:"size"`      // quota in bytes (0 = unlimited)
+	UsedSize  int64     `json:"used_size"` // bytes used, trash and versions included
+	Pack      DrivePack `json:"pack"`
+	CreatedAt int64     `json:"created_at"`
+}
+
+// DrivePack is the subscription plan of a drive
+type DrivePack struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+// DriveSizes splits the used size of a drive
+type DriveSizes struct {
+	Files    int64 `json:"files"`    // current versions of the files
+	Trash    int64 `json:"trash"`    // files in the trash
+	Versions int64 `json:"versions"` // previous versions of the files
+}
+
+// UserUsage is the space used by the files a user owns
+type UserUsage struct {
+	UserID   int   `json:"user_id"`
+	UsedSize int64 `json:"used_size"`
+}
+
+// GetDrive returns the drive of the client
+func (c *Client) GetDrive(ctx context.Context) (*Drive, error) {
+	path := fmt.Sprintf("/2/drive/%d", c.driveID)
+	return call[*Drive](ctx, c, http.MethodGet, path, nil)
+}
+
+// GetDriveSizes returns the space used by files, trash and versions
+func (c *Client) GetDriveSizes(ctx context.Context) (*DriveSizes, error) {
+	path := fmt.Sprintf("/2/drive/%d/statistics/sizes", c.driveID)
+	return call[*DriveSizes](ctx, c, http.MethodGet, path, nil)
+}
+
+// UsersUsage returns the space used by each user of the drive
+func (c *Client) UsersUsage(ctx context.Context) ([]UserUsage, error) {
+	path := fmt.Sprintf("/2/drive/%d/statistics/users", c.driveID)
+	return call[[]UserUsage](ctx, c, http.MethodGet, path, nil)
+}
@@ -0,0 +1,101 @@
+package kdrive
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Sentinel errors matched by *APIError through errors.Is
+var (
+	ErrUnauthorized = errors.New("unauthorized (invalid or expired token)")
+	ErrForbidden    = errors.New("forbidden")
+	ErrNotFound     = errors.New("not found")
+	ErrRateLimited  = errors.New("rate limited")
+	ErrConflict     = errors.New("already exists")
+)
+
+// APIError is returned by API calls that completed with an HTTP error status.
+// It matches ErrUnauthorized, ErrForbidden, ErrNotFound and ErrRateLimited
+// with errors.Is according to its status code, and ErrConflict on a 409 or an
+// "..._already_exists" error code.
+type APIError struct {
+	StatusCode  int
+	Method      string
+	Endpoint    string // request path, including query string
+	Code        string // API error code from the JSON body (e.g. "object_not_found")
+	Description string // API error description from the JSON body
+	Body        string // raw response body
+	RetryAfter  time.Duration
+}
+
+// newError builds an *APIError, parsing the standard {"result":"error","error":{...}} body
+func newError(method, endpoint string, status int, body []byte) *APIError {
+	e := &APIError{StatusCode: status, Method: method, Endpoint: endpoint, Body: string(body)}
+
+	var parsed struct {
+		Error struct {
+			Code        string `json:"code"`
+			Description string `json:"description"`
+		} `json:"error"`
+	}
+	if json.Unmarshal(body, &parsed) == nil {
+		e.Code = parsed.Error.Code
+		e.Description = parsed.Error.Description
+	}
+	return e
+}
+
+func (e *APIError) Error() string {
+	detail := e.Body
+	if e.Code != "" {
+		detail = e.Code
+		if e.Description != "" {
+			detail += ": " + e.Description
+		}
+	}
+	return fmt.Sprintf("API error (%d) on %s %s: %s", e.StatusCode, e.Method, e.Endpoint, detail)
+}
+
+// Is reports whether the error's status matches one of the sentinel errors
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized
+	case ErrForbidden:
+		return e.StatusCode == http.StatusForbidden
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	case ErrConflict:
+		return e.StatusCode == http.StatusConflict || strings.HasSuffix(e.Code, "already_exists")
+	}
+	return false
+}
+
+// StatusCode returns the HTTP status of an API error, or 0 if err is not an *APIError
+func StatusCode(err error) int {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode
+	}
+	return 0
+}
+
+// timeoutError is a request that did not complete within the per-request timeout
+type timeoutError struct {
+	method string
+	path   string
+}
+
+func (e *timeoutError) Error() string {
+	return fmt.Sprintf("request timeout after 30s: %s %s", e.method, e.path)
+}
+
+func (e *timeoutError) Timeout() bool {
+	return true
+}
@@ -0,0 +1,117 @@
+package kdrive
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// HasGlob reports whether a drive path contains glob metacharacters (*, ? or [)
+func HasGlob(p string) bool {
+	return strings.ContainsAny(p, "*?[")
+}
+
+// Glob returns the files and directories matching a path pattern, sorted by
+// path. Segments use path.Match syntax, and a "**" segment matches any number
+// of directories ("Projects/*/Invoices", "**/*.tmp"). Names are compared
+// case-insensitively unless WithCaseSensitivePaths is set.
+//
+// The literal prefix of the pattern is resolved with FindFileByPath and the
+// rest is matched during a single walk, pruning directories that cannot lead
+// to a match. Unlistable directories are skipped and reported with a
+// *PartialError along with the matches found elsewhere.
+func (c *Client) Glob(ctx context.Context, pattern string) ([]File, error) {
+	segs := strings.Split(strings.Trim(pattern, "/"), "/")
+	literal := len(segs)
+	for i, seg := range segs {
+		if seg == "**" || HasGlob(seg) {
+			literal = min(literal, i)
+		}
+		if seg == "**" {
+			continue
+		}
+		if _, err := path.Match(seg, ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern '%s': %w", pattern, err)
+		}
+	}
+
+	base, err := c.FindFileByPath(ctx, strings.Join(segs[:literal], "/"))
+	if err != nil {
+		return nil, err
+	}
+	rest := segs[literal:]
+	if len(rest) == 0 {
+		return []File{*base}, nil
+	}
+	if base.Type != "dir" {
+		return nil, fmt.Errorf("%s is not a directory", base.Path)
+	}
+
+	opts := WalkOptions{RootPath: base.Path}
+	if !strings.Contains(pattern, "**") {
+		opts.MaxDepth = len(rest) // no match can be deeper
+	}
+	relative := func(f *File) []string {
+		return strings.Split(strings.TrimPrefix(f.Path[len(base.Path):], "/"), "/")
+	}
+	opts.Prune = func(dir *File) bool {
+		return !matchPrefix(rest, relative(dir), !c.exactCase)
+	}
+
+	var matches []File
+	err = c.Walk(ctx, base.ID, base.Name, nil, opts, func(f *File) error {
+		if matchSegments(rest, relative(f), !c.exactCase) {
+			matches = append(matches, *f)
+		}
+		return nil
+	})
+	sort.Slice(matches, func(i, j int) bool {
+		return matches[i].Path < matches[j].Path
+	})
+	return matches, err
+}
+
+// matchSegments reports whether the path segments name match the pattern segments
+func matchSegments(pattern, name []string, fold bool) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:], fold) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 || !matchName(pattern[0], name[0], fold) {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
+
+// matchPrefix reports whether entries below the directory dir may match the pattern
+func matchPrefix(pattern, dir []string, fold bool) bool {
+	for ; len(dir) > 0; pattern, dir = pattern[1:], dir[1:] {
+		if len(pattern) == 0 {
+			return false
+		}
+		if pattern[0] == "**" {
+			return true
+		}
+		if !matchName(pattern[0], dir[0], fold) {
+			return false
+		}
+	}
+	return true
+}
+
+func matchName(pattern, name string, fold bool) bool {
+	if fold {
+		pattern, name = strings.ToLower(pattern), strings.ToLower(name)
+	}
+	ok, _ := path.Match(pattern, name)
+	return ok
+}
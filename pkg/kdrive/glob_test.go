@@ -0,0 +1,59 @@
+package kdrive
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMatchSegments(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		fold    bool
+		want    bool
+	}{
+		{"*.pdf", "a.pdf", false, true},
+		{"*.pdf", "a.PDF", false, false},
+		{"*.pdf", "a.PDF", true, true},
+		{"*.pdf", "sub/a.pdf", false, false},
+		{"*/Invoices", "2024/Invoices", false, true},
+		{"*/Invoices", "2024/Invoices/a.pdf", false, false},
+		{"**/*.tmp", "a.tmp", false, true},
+		{"**/*.tmp", "x/y/a.tmp", false, true},
+		{"**/*.tmp", "x/y/a.txt", false, false},
+		{"x/**", "x", false, true},
+		{"x/**", "x/y/z", false, true},
+		{"x/**/z", "x/z", false, true},
+		{"x/**/z", "x/a/b/z", false, true},
+		{"x/**/z", "x/a/b/z/c", false, false},
+		{"[ab]?.txt", "b1.txt", false, true},
+		{"[ab]?.txt", "c1.txt", false, false},
+	}
+	for _, tt := range tests {
+		got := matchSegments(strings.Split(tt.pattern, "/"), strings.Split(tt.name, "/"), tt.fold)
+		if got != tt.want {
+			t.Errorf("matchSegments(%q, %q, %v) = %v, want %v", tt.pattern, tt.name, tt.fold, got, tt.want)
+		}
+	}
+}
+
+func TestMatchPrefix(t *testing.T) {
+	tests := []struct {
+		pattern string
+		dir     string
+		want    bool
+	}{
+		{"*/Invoices/*.pdf", "2024", true},
+		{"*/Invoices/*.pdf", "2024/Invoices", true},
+		{"*/Invoices/*.pdf", "2024/Other", false},
+		{"*/Invoices/*.pdf", "2024/Invoices/old", false},
+		{"Projects/**", "Projects/a/b/c", true},
+		{"Projects/**", "Archive", false},
+	}
+	for _, tt := range tests {
+		got := matchPrefix(strings.Split(tt.pattern, "/"), strings.Split(tt.dir, "/"), false)
+		if got != tt.want {
+			t.Errorf("matchPrefix(%q, %q) = %v, want %v", tt.pattern, tt.dir, got, tt.want)
+		}
+	}
+}
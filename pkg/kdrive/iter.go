@@ -0,0 +1,154 @@
+package kdrive
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// Iterators stream paginated endpoints lazily: a page is only requested once
+// the previous one has been consumed, and breaking out of the loop stops
+// pagination. An error is yielded once, as the last element:
+//
+//	for f, err := range client.Files(ctx, dirID) {
+//		if err != nil {
+//			return err
+//		}
+//		...
+//	}
+
+// Collect gathers every element of an iterator, stopping at the first error
+func Collect[T any](seq iter.Seq2[T, error]) ([]T, error) {
+	var all []T
+	for v, err := range seq {
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, v)
+	}
+	return all, nil
+}
+
+// pageFunc fetches the page after cursor ("" for the first one)
+type pageFunc[T any] func(cursor string) (items []T, next string, hasMore bool, err error)
+
+// paginate turns a cursor-paginated endpoint into an iterator
+func paginate[T any](ctx context.Context, fetch pageFunc[T]) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+		cursor := ""
+		for {
+			if err := ctx.Err(); err != nil {
+				yield(zero, err)
+				return
+			}
+			items, next, hasMore, err := fetch(cursor)
+			if err != nil {
+				yield(zero, err)
+				return
+			}
+			for _, item := range items {
+				if !yield(item, nil) {
+					return
+				}
+			}
+			if !hasMore {
+				return
+			}
+			cursor = next
+		}
+	}
+}
+
+// Files iterates over the direct children of a directory
+func (c *Client) Files(ctx context.Context, dirID int) iter.Seq2[File, error] {
+	return c.files(ctx, dirID, "")
+}
+
+// FilesWithCategories is like Files but populates the categories of each file
+func (c *Client) FilesWithCategories(ctx context.Context, dirID int) iter.Seq2[File, error] {
+	return c.files(ctx, dirID, "file.categories")
+}
+
+func (c *Client) files(ctx context.Context, dirID int, with string) iter.Seq2[File, error] {
+	base := fmt.Sprintf("/3/drive/%d/files/%d/files", c.driveID, dirID)
+
+	return paginate(ctx, func(cursor string) ([]File, string, bool, error) {
+		q := url.Values{}
+		if with != "" {
+			q.Set("with", with)
+		}
+		if cursor != "" {
+			q.Set("cursor", cursor)
+		}
+		reqPath := base
+		if len(q) > 0 {
+			reqPath = base + "?" + q.Encode()
+		}
+		return c.filesPage(ctx, reqPath)
+	})
+}
+
+// FilesByCategory iterates over every file carrying the given category, drive-wide
+func (c *Client) FilesByCategory(ctx context.Context, categoryID int) iter.Seq2[File, error] {
+	base := fmt.Sprintf("/3/drive/%d/files/search", c.driveID)
+
+	return paginate(ctx, func(cursor string) ([]File, string, bool, error) {
+		q := url.Values{}
+		q.Set("category", strconv.Itoa(categoryID))
+		q.Set("limit", "1000")
+		if cursor != "" {
+			q.Set("cursor", cursor)
+		}
+		return c.filesPage(ctx, base+"?"+q.Encode())
+	})
+}
+
+func (c *Client) filesPage(ctx context.Context, reqPath string) ([]File, string, bool, error) {
+	resp, err := callPage[[]File](ctx, c, http.MethodGet, reqPath, nil)
+	if err != nil {
+		return nil, "", false, err
+	}
+	return resp.Data, resp.Cursor, resp.HasMore, nil
+}
+
+// Activities iterates over the activity log, following cursors from opts.Cursor
+// (opts.Limit is the page size)
+func (c *Client) Activities(ctx context.Context, opts ActivitiesOptions) iter.Seq2[Activity, error] {
+	start := opts.Cursor
+	return paginate(ctx, func(cursor string) ([]Activity, string, bool, error) {
+		if cursor == "" {
+			cursor = start
+		}
+		opts.Cursor = cursor
+		return c.ListActivities(ctx, opts)
+	})
+}
+
+// Reports iterates over the activity reports, page by page
+func (c *Client) Reports(ctx context.Context) iter.Seq2[Report, error] {
+	return func(yield func(Report, error) bool) {
+		for page := 1; ; page++ {
+			if err := ctx.Err(); err != nil {
+				yield(Report{}, err)
+				return
+			}
+			reports, pages, err := c.ListReports(ctx, page)
+			if err != nil {
+				yield(Report{}, err)
+				return
+			}
+			for _, r := range reports {
+				if !yield(r, nil) {
+					return
+				}
+			}
+			if page >= pages {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,216 @@
+package kdrive
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RootID is the ID of the drive root directory
+const RootID = 1
+
+// PathEntry is an indexed file path
+type PathEntry struct {
+	ID        int    `json:"id"`
+	Path      string `json:"path"`
+	UpdatedAt int64  `json:"updated_at,omitempty"` // File.UpdatedAt when indexed, used to detect changes
+	IndexedAt int64  `json:"indexed_at"`           // Unix time the entry was recorded
+}
+
+// PathIndex maps file IDs to drive paths ("/Common documents/Invoices") and
+// back. Lookups match the exact case first, then a unique case-insensitive
+// match. It is safe for concurrent use.
+type PathIndex struct {
+	mu     sync.RWMutex
+	byID   map[int]PathEntry
+	byPath map[string]int   // exact path
+	byFold map[string][]int // lowercased path, several IDs when paths differ only by case
+	dirty  bool
+}
+
+// NewPathIndex returns an index knowing only the drive root
+func NewPathIndex() *PathIndex {
+	x := &PathIndex{
+		byID:   make(map[int]PathEntry),
+		byPath: make(map[string]int),
+		byFold: make(map[string][]int),
+	}
+	x.AddEntry(PathEntry{ID: RootID, Path: "/"})
+	x.dirty = false
+	return x
+}
+
+// Add records the path of a file
+func (x *PathIndex) Add(id int, path string) {
+	x.AddEntry(PathEntry{ID: id, Path: path})
+}
+
+// AddFile records the path of a listed file, with its UpdatedAt for validation
+func (x *PathIndex) AddFile(f *File) {
+	x.AddEntry(PathEntry{ID: f.ID, Path: f.Path, UpdatedAt: f.UpdatedAt})
+}
+
+// AddEntry records an entry, replacing any previous path of the same ID
+func (x *PathIndex) AddEntry(e PathEntry) {
+	e.Path = CleanPath(e.Path)
+	if e.IndexedAt == 0 {
+		e.IndexedAt = time.Now().Unix()
+	}
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if old, ok := x.byID[e.ID]; ok {
+		if old.Path == e.Path && old.UpdatedAt == e.UpdatedAt {
+			x.byID[e.ID] = e // refresh IndexedAt
+			x.dirty = true
+			return
+		}
+		x.unlink(old) // moved or renamed
+	}
+	x.byID[e.ID] = e
+	x.byPath[e.Path] = e.ID
+	fold := strings.ToLower(e.Path)
+	x.byFold[fold] = append(x.byFold[fold], e.ID)
+	x.dirty = true
+}
+
+// Remove forgets a file (e.g. a stale entry pointing to a deleted file)
+func (x *PathIndex) Remove(id int) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if e, ok := x.byID[id]; ok {
+		x.unlink(e)
+		delete(x.byID, id)
+		x.dirty = true
+	}
+}
+
+func (x *PathIndex) unlink(e PathEntry) {
+	if x.byPath[e.Path] == e.ID {
+		delete(x.byPath, e.Path)
+	}
+	fold := strings.ToLower(e.Path)
+	ids := x.byFold[fold]
+	for i, id := range ids {
+		if id == e.ID {
+			ids = append(ids[:i:i], ids[i+1:]...)
+			break
+		}
+	}
+	if len(ids) == 0 {
+		delete(x.byFold, fold)
+	} else {
+		x.byFold[fold] = ids
+	}
+}
+
+// Path returns the path of a file ID
+func (x *PathIndex) Path(id int) (string, bool) {
+	e, ok := x.Entry(id)
+	return e.Path, ok
+}
+
+// Entry returns the entry of a file ID
+func (x *PathIndex) Entry(id int) (PathEntry, bool) {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	e, ok := x.byID[id]
+	return e, ok
+}
+
+// ID returns the file ID of a path: the exact-case match, or else the only
+// case-insensitive one (unless caseSensitive)
+func (x *PathIndex) ID(path string, caseSensitive bool) (int, bool) {
+	path = CleanPath(path)
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	if id, ok := x.byPath[path]; ok {
+		return id, true
+	}
+	if caseSensitive {
+		return 0, false
+	}
+	if ids := x.byFold[strings.ToLower(path)]; len(ids) == 1 {
+		return ids[0], true
+	}
+	return 0, false
+}
+
+// Len returns the number of indexed files
+func (x *PathIndex) Len() int {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	return len(x.byID)
+}
+
+// Entries returns a copy of every entry
+func (x *PathIndex) Entries() []PathEntry {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	entries := make([]PathEntry, 0, len(x.byID))
+	for _, e := range x.byID {
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+// Dirty reports whether the index changed since it was created or since
+// the last MarkClean (used to skip saving an unchanged persistent index)
+func (x *PathIndex) Dirty() bool {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	return x.dirty
+}
+
+// MarkClean resets Dirty, e.g. after the index has been loaded or saved
+func (x *PathIndex) MarkClean() {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.dirty = false
+}
+
+// CleanPath normalizes a drive path: leading slash, no trailing slash ("/" for the root)
+func CleanPath(path string) string {
+	return "/" + strings.Trim(path, "/")
+}
+
+// JoinPath returns the path of the entry name in directory dir
+func JoinPath(dir, name string) string {
+	return strings.TrimSuffix(dir, "/") + "/" + name
+}
+
+// Paths returns the path index of the client, filled by walks and path lookups
+func (c *Client) Paths() *PathIndex {
+	return c.paths
+}
+
+// FilePath returns the drive path of a file, walking up its parents (and
+// caching them in the path index) when it is not indexed yet.
+func (c *Client) FilePath(ctx context.Context, fileID int) (string, error) {
+	if path, ok := c.paths.Path(fileID); ok {
+		return path, nil
+	}
+
+	f, err := c.GetFile(ctx, fileID)
+	if err != nil {
+		return "", err
+	}
+
+	var path string
+	switch {
+	case f.ParentID == 0 || f.ParentID == f.ID:
+		path = "/" + f.Name
+	default:
+		parent, err := c.FilePath(ctx, f.ParentID)
+		if err != nil {
+			return "", fmt.Errorf("path of %d: %w", fileID, err)
+		}
+		path = JoinPath(parent, f.Name)
+	}
+
+	f.Path = path
+	c.paths.AddFile(f)
+	return path, nil
+}
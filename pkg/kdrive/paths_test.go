@@ -0,0 +1,94 @@
+package kdrive
+
+import "testing"
+
+func TestPathIndex(t *testing.T) {
+	x := NewPathIndex()
+	if x.Dirty() {
+		t.Error("new index is dirty")
+	}
+	x.Add(10, "/Docs/")
+	x.Add(11, "/DOCS")
+	x.Add(12, "/Docs/Invoices")
+	x.Add(13, "/Photos")
+	x.Add(12, "/Photos/Invoices") // moved
+	x.Remove(13)
+	if !x.Dirty() {
+		t.Error("index not dirty after changes")
+	}
+
+	tests := []struct {
+		path          string
+		caseSensitive bool
+		id            int
+		ok            bool
+	}{
+		{"/", true, RootID, true},
+		{"", true, RootID, true},
+		{"/Docs", true, 10, true},
+		{"Docs/", true, 10, true},
+		{"/DOCS", false, 11, true},
+		{"/docs", true, 0, false},
+		{"/docs", false, 0, false}, // matches /Docs and /DOCS
+		{"/Docs/Invoices", false, 0, false},
+		{"/Photos/Invoices", true, 12, true},
+		{"/photos/invoices", false, 12, true},
+		{"/Photos", false, 0, false},
+	}
+	for _, tt := range tests {
+		id, ok := x.ID(tt.path, tt.caseSensitive)
+		if id != tt.id || ok != tt.ok {
+			t.Errorf("ID(%q, %v) = %d, %v, want %d, %v", tt.path, tt.caseSensitive, id, ok, tt.id, tt.ok)
+		}
+	}
+
+	if p, _ := x.Path(12); p != "/Photos/Invoices" {
+		t.Errorf("Path(12) = %q", p)
+	}
+	if x.Len() != 4 {
+		t.Errorf("Len = %d, want 4", x.Len())
+	}
+
+	// Removing one of two case variants makes the other one unique
+	x.Remove(11)
+	if id, ok := x.ID("/docs", false); id != 10 || !ok {
+		t.Errorf("ID(/docs) after removal = %d, %v, want 10, true", id, ok)
+	}
+	x.MarkClean()
+	if x.Dirty() {
+		t.Error("index dirty after MarkClean")
+	}
+}
+
+func TestPathIndexAddFile(t *testing.T) {
+	x := NewPathIndex()
+	x.AddFile(&File{ID: 20, Path: "/a.txt", UpdatedAt: 100})
+	x.AddFile(&File{ID: 20, Path: "/a.txt", UpdatedAt: 200})
+	e, ok := x.Entry(20)
+	if !ok || e.UpdatedAt != 200 || e.IndexedAt == 0 {
+		t.Errorf("Entry(20) = %+v, %v", e, ok)
+	}
+	if id, ok := x.ID("/a.txt", true); id != 20 || !ok {
+		t.Errorf("ID(/a.txt) = %d, %v", id, ok)
+	}
+}
+
+func TestCleanAndJoinPath(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"", "/"},
+		{"/", "/"},
+		{"a/b/", "/a/b"},
+		{"//a//", "/a"},
+	}
+	for _, tt := range tests {
+		if got := CleanPath(tt.in); got != tt.want {
+			t.Errorf("CleanPath(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+	if got := JoinPath("/", "a"); got != "/a" {
+		t.Errorf("JoinPath(/, a) = %q", got)
+	}
+	if got := JoinPath("/a", "b"); got != "/a/b" {
+		t.Errorf("JoinPath(/a, b) = %q", got)
+	}
+}
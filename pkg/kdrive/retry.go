@@ -0,0 +1,100 @@
+package kdrive
+
+import (
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+// retryPolicy decides whether a failed request is retried and after which delay.
+// 429 responses are always retried (the request was not processed). 502/503/504
+// responses and transient network errors are only retried for idempotent methods.
+type retryPolicy struct {
+	maxAttempts int
+	budget      time.Duration
+	baseDelay   time.Duration
+	maxDelay    time.Duration
+}
+
+func newRetryPolicy(maxAttempts int, budget time.Duration) retryPolicy {
+	p := retryPolicy{
+		maxAttempts: maxAttempts,
+		budget:      budget,
+		baseDelay:   500 * time.Millisecond,
+		maxDelay:    30 * time.Second,
+	}
+	if p.maxAttempts <= 0 {
+		p.maxAttempts = 4
+	}
+	if p.budget <= 0 {
+		p.budget = 2 * time.Minute
+	}
+	return p
+}
+
+// decide returns whether to retry after the given (1-based) attempt failed with err
+func (p retryPolicy) decide(method string, attempt int, elapsed time.Duration, err error) (bool, time.Duration) {
+	if attempt >= p.maxAttempts {
+		return false, 0
+	}
+
+	var apiErr *APIError
+	switch {
+	case errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusTooManyRequests:
+	case errors.As(err, &apiErr) && isRetryableStatus(apiErr.StatusCode) && isIdempotent(method):
+	case apiErr == nil && isTransient(err) && isIdempotent(method):
+	default:
+		return false, 0
+	}
+
+	delay := p.backoff(attempt)
+	if apiErr != nil && apiErr.RetryAfter > delay {
+		delay = apiErr.RetryAfter
+	}
+	if elapsed+delay > p.budget {
+		return false, 0
+	}
+	return true, delay
+}
+
+// backoff returns a "full jitter" exponential delay: random in [0, min(max, base*2^(attempt-1))]
+func (p retryPolicy) backoff(attempt int) time.Duration {
+	ceiling := p.baseDelay << (attempt - 1)
+	if ceiling > p.maxDelay || ceiling <= 0 {
+		ceiling = p.maxDelay
+	}
+	return time.Duration(rand.Int64N(int64(ceiling) + 1))
+}
+
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+func isRetryableStatus(status int) bool {
+	return status == http.StatusBadGateway || status == http.StatusServiceUnavailable || status == http.StatusGatewayTimeout
+}
+
+// isTransient reports network errors worth retrying: timeouts, resets, truncated responses
+func isTransient(err error) bool {
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	var timeout *timeoutError
+	if errors.As(err, &timeout) {
+		return true
+	}
+	return errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.EPIPE) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, io.EOF)
+}
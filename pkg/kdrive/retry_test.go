@@ -0,0 +1,65 @@
+package kdrive
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicyDecide(t *testing.T) {
+	p := newRetryPolicy(3, time.Minute)
+	status := func(code int) error { return &APIError{StatusCode: code} }
+
+	tests := []struct {
+		name    string
+		method  string
+		attempt int
+		elapsed time.Duration
+		err     error
+		retry   bool
+	}{
+		{"429 on POST", http.MethodPost, 1, 0, status(http.StatusTooManyRequests), true},
+		{"503 on GET", http.MethodGet, 1, 0, status(http.StatusServiceUnavailable), true},
+		{"502 on PUT", http.MethodPut, 2, 0, status(http.StatusBadGateway), true},
+		{"503 on POST", http.MethodPost, 1, 0, status(http.StatusServiceUnavailable), false},
+		{"500 on GET", http.MethodGet, 1, 0, status(http.StatusInternalServerError), false},
+		{"404 on GET", http.MethodGet, 1, 0, status(http.StatusNotFound), false},
+		{"connection reset on GET", http.MethodGet, 1, 0, fmt.Errorf("read: %w", syscall.ECONNRESET), true},
+		{"unexpected EOF on DELETE", http.MethodDelete, 1, 0, io.ErrUnexpectedEOF, true},
+		{"connection reset on POST", http.MethodPost, 1, 0, syscall.ECONNRESET, false},
+		{"other error", http.MethodGet, 1, 0, errors.New("boom"), false},
+		{"last attempt", http.MethodGet, 3, 0, status(http.StatusTooManyRequests), false},
+		{"budget spent", http.MethodGet, 1, 2 * time.Minute, status(http.StatusTooManyRequests), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			retry, delay := p.decide(tt.method, tt.attempt, tt.elapsed, tt.err)
+			if retry != tt.retry {
+				t.Fatalf("retry = %v, want %v", retry, tt.retry)
+			}
+			if !retry && delay != 0 {
+				t.Errorf("delay = %v without retry", delay)
+			}
+			if retry && (delay < 0 || delay > p.maxDelay) {
+				t.Errorf("delay = %v, want within [0, %v]", delay, p.maxDelay)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyDecideRetryAfter(t *testing.T) {
+	p := newRetryPolicy(3, time.Minute)
+
+	err := &APIError{StatusCode: http.StatusTooManyRequests, RetryAfter: 40 * time.Second}
+	if retry, delay := p.decide(http.MethodGet, 1, 0, err); !retry || delay != 40*time.Second {
+		t.Errorf("decide = %v, %v, want true, 40s", retry, delay)
+	}
+	// Retry-After beyond the remaining budget gives up
+	if retry, _ := p.decide(http.MethodGet, 1, 30*time.Second, err); retry {
+		t.Error("retried beyond the budget")
+	}
+}
@@ -0,0 +1,239 @@
+package kdrive
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// Share link rights
+const (
+	LinkPublic   = "public"   // anyone with the URL
+	LinkPassword = "password" // anyone with the URL and the password
+	LinkInherit  = "inherit"  // drive users with access to the file
+)
+
+// ShareLink is the public link of a file or directory
+type ShareLink struct {
+	URL           string                `json:"url"`
+	FileID        int                   `json:"file_id"`
+	Right         string                `json:"right"`       // LinkPublic, LinkPassword or LinkInherit
+	ValidUntil    int64                 `json:"valid_until"` // 0 = never expires
+	CreatedBy     int                   `json:"created_by"`
+	CreatedAt     int64                 `json:"created_at"`
+	UpdatedAt     int64                 `json:"updated_at"`
+	AccessBlocked bool                  `json:"access_blocked"`
+	ViewsCount    int                   `json:"views_count"`
+	Capabilities  ShareLinkCapabilities `json:"capabilities"`
+}
+
+// ShareLinkCapabilities are the rights granted to share link visitors
+type ShareLinkCapabilities struct {
+	CanEdit     bool `json:"can_edit"`
+	CanSeeStats bool `json:"can_see_stats"`
+	CanSeeInfo  bool `json:"can_see_info"`
+	CanDownload bool `json:"can_download"`
+	CanComment  bool `json:"can_comment"`
+}
+
+// FileAccess lists the users, teams and pending invitations having access to a file
+type FileAccess struct {
+	Users       []UserAccess `json:"users"`
+	Teams       []TeamAccess `json:"teams"`
+	Invitations []Invitation `json:"invitations"`
+}
+
+// UserAccess is the access of a drive user to a file
+type UserAccess struct {
+	ID     int    `json:"id"`
+	Name   string `json:"name"`
+	Email  string `json:"email"`
+	Right  string `json:"right"` // read, write, manage
+	Status string `json:"status"`
+}
+
+// TeamAccess is the access of a team to a file
+type TeamAccess struct {
+	ID    int    `json:"id"`
+	Name  string `json:"name"`
+	Right string `json:"right"`
+}
+
+// Invitation is a share to an email address not (yet) belonging to a drive user
+type Invitation struct {
+	ID     int    `json:"id"`
+	Email  string `json:"email"`
+	Name   string `json:"name"`
+	Right  string `json:"right"`
+	Status string `json:"status"`
+	UserID int    `json:"user_id,omitempty"`
+}
+
+// GetShareLink returns the share link of a file. It fails with ErrNotFound
+// when the file has none.
+func (c *Client) GetShareLink(ctx context.Context, fileID int) (*ShareLink, error) {
+	path := fmt.Sprintf("/2/drive/%d/files/%d/link", c.driveID, fileID)
+	link, err := call[*ShareLink](ctx, c, http.MethodGet, path, nil)
+	if err == nil && link == nil {
+		return nil, fmt.Errorf("share link of %d: %w", fileID, ErrNotFound)
+	}
+	return link, err
+}
+
+// ShareLinkSettings configures a share link. Zero fields are left unchanged by
+// UpdateShareLink and use the API defaults on creation.
+type ShareLinkSettings struct {
+	Right       string // LinkPublic, LinkPassword or LinkInherit
+	Password    string // required with LinkPassword
+	ValidUntil  int64  // expiry (Unix time)
+	NoExpiry    bool   // remove the expiry (update only)
+	CanDownload *bool
+	CanEdit     *bool
+	CanComment  *bool
+	CanSeeStats *bool
+	CanSeeInfo  *bool
+}
+
+// MarshalJSON encodes only the fields set, with a null valid_until for NoExpiry
+func (s ShareLinkSettings) MarshalJSON() ([]byte, error) {
+	body := make(map[string]any)
+	if s.Right != "" {
+		body["right"] = s.Right
+	}
+	if s.Password != "" {
+		body["password"] = s.Password
+	}
+	switch {
+	case s.NoExpiry:
+		body["valid_until"] = nil
+	case s.ValidUntil != 0:
+		body["valid_until"] = s.ValidUntil
+	}
+	for name, v := range map[string]*bool{
+		"can_download":  s.CanDownload,
+		"can_edit":      s.CanEdit,
+		"can_comment":   s.CanComment,
+		"can_see_stats": s.CanSeeStats,
+		"can_see_info":  s.CanSeeInfo,
+	} {
+		if v != nil {
+			body[name] = *v
+		}
+	}
+	return json.Marshal(body)
+}
+
+// CreateShareLink creates the share link of a file
+func (c *Client) CreateShareLink(ctx context.Context, fileID int, settings ShareLinkSettings) (*ShareLink, error) {
+	path := fmt.Sprintf("/2/drive/%d/files/%d/link", c.driveID, fileID)
+	return call[*ShareLink](ctx, c, http.MethodPost, path, settings)
+}
+
+// UpdateShareLink changes the settings of the share link of a file
+func (c *Client) UpdateShareLink(ctx context.Context, fileID int, settings ShareLinkSettings) error {
+	path := fmt.Sprintf("/2/drive/%d/files/%d/link", c.driveID, fileID)
+	_, err := call[bool](ctx, c, http.MethodPut, path, settings)
+	return err
+}
+
+// DeleteShareLink removes the share link of a file
+func (c *Client) DeleteShareLink(ctx context.Context, fileID int) error {
+	path := fmt.Sprintf("/2/drive/%d/files/%d/link", c.driveID, fileID)
+	_, err := call[bool](ctx, c, http.MethodDelete, path, nil)
+	return err
+}
+
+// GetFileAccess returns who has access to a file, inherited access included
+func (c *Client) GetFileAccess(ctx context.Context, fileID int) (*FileAccess, error) {
+	path := fmt.Sprintf("/2/drive/%d/files/%d/access", c.driveID, fileID)
+	return call[*FileAccess](ctx, c, http.MethodGet, path, nil)
+}
+
+// FileAccesses fetches the access of several files concurrently (configured
+// workers, paced by the rate limiter). progress, if not nil, is called after
+// each file. Files whose access cannot be read are missing from the result and
+// reported in the joined error; other results are still returned.
+func (c *Client) FileAccesses(ctx context.Context, fileIDs []int, progress func(done, total int)) (map[int]*FileAccess, error) {
+	return fetchEach(ctx, c, fileIDs, "access", c.GetFileAccess, progress)
+}
+
+// fetchEach calls fetch for every file ID with the configured workers,
+// returning the results by ID and the failures as a joined error
+func fetchEach[T any](ctx context.Context, c *Client, fileIDs []int, what string, fetch func(context.Context, int) (T, error), progress func(done, total int)) (map[int]T, error) {
+	type result struct {
+		id  int
+		v   T
+		err error
+	}
+
+	jobs := make(chan int)
+	results := make(chan result)
+	var wg sync.WaitGroup
+	for i := 0; i < c.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for id := range jobs {
+				v, err := fetch(ctx, id)
+				results <- result{id: id, v: v, err: err}
+			}
+		}()
+	}
+	go func() {
+		defer close(jobs)
+		for _, id := range fileIDs {
+			select {
+			case jobs <- id:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	values := make(map[int]T, len(fileIDs))
+	var errs []error
+	done := 0
+	for r := range results {
+		done++
+		if r.err != nil {
+			errs = append(errs, fmt.Errorf("%s of %d: %w", what, r.id, r.err))
+		} else {
+			values[r.id] = r.v
+		}
+		if progress != nil {
+			progress(done, len(fileIDs))
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return values, err
+	}
+	return values, errors.Join(errs...)
+}
+
+// RemoveUserAccess removes the access of a user to a file
+func (c *Client) RemoveUserAccess(ctx context.Context, fileID, userID int) error {
+	path := fmt.Sprintf("/2/drive/%d/files/%d/access/users/%d", c.driveID, fileID, userID)
+	_, err := call[bool](ctx, c, http.MethodDelete, path, nil)
+	return err
+}
+
+// RemoveTeamAccess removes the access of a team to a file
+func (c *Client) RemoveTeamAccess(ctx context.Context, fileID, teamID int) error {
+	path := fmt.Sprintf("/2/drive/%d/files/%d/access/teams/%d", c.driveID, fileID, teamID)
+	_, err := call[bool](ctx, c, http.MethodDelete, path, nil)
+	return err
+}
+
+// DeleteInvitation cancels an invitation
+func (c *Client) DeleteInvitation(ctx context.Context, invitationID int) error {
+	path := fmt.Sprintf("/2/drive/%d/files/invitations/%d", c.driveID, invitationID)
+	_, err := call[bool](ctx, c, http.MethodDelete, path, nil)
+	return err
+}
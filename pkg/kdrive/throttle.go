@@ -0,0 +1,164 @@
+package kdrive
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// RateLimiter paces requests with a token bucket. When adaptive, its rate follows
+// AIMD: it grows by a fixed step on each healthy response and is halved on
+// 429/5xx responses, within [min, max]. It also honors Retry-After and
+// rate limit quota headers by pausing every request.
+type RateLimiter struct {
+	limiter  *rate.Limiter
+	adaptive bool
+	min      rate.Limit
+	max      rate.Limit
+	step     rate.Limit
+
+	mu          sync.Mutex
+	pausedUntil time.Time
+}
+
+// NewRateLimiter builds a rate limiter starting at rps requests per second with
+// the given token bucket burst. When adaptive, the rate grows while responses
+// are healthy, up to maxRPS, and is halved on 429/5xx responses. Share one
+// RateLimiter between clients (WithRateLimiter) so they draw from the same budget.
+func NewRateLimiter(rps float64, burst int, maxRPS float64, adaptive bool) *RateLimiter {
+	start := rate.Limit(rps)
+	if start <= 0 {
+		start = 2 // conservative to avoid API hangups
+	}
+	if burst <= 0 {
+		burst = 5
+	}
+	ceiling := rate.Limit(maxRPS)
+	if ceiling < start {
+		ceiling = start
+	}
+
+	return &RateLimiter{
+		limiter:  rate.NewLimiter(start, burst),
+		adaptive: adaptive,
+		min:      start / 4,
+		max:      ceiling,
+		step:     start / 20,
+	}
+}
+
+// Unlimited returns a rate limiter that never delays requests (e.g. when replaying recordings)
+func Unlimited() *RateLimiter {
+	return &RateLimiter{limiter: rate.NewLimiter(rate.Inf, 1)}
+}
+
+// Wait blocks until a request may be sent
+func (t *RateLimiter) Wait(ctx context.Context) error {
+	return t.wait(ctx, slog.New(slog.DiscardHandler))
+}
+
+// wait is Wait, logging pauses to log
+func (t *RateLimiter) wait(ctx context.Context, log *slog.Logger) error {
+	t.mu.Lock()
+	pause := time.Until(t.pausedUntil)
+	t.mu.Unlock()
+
+	if pause > 0 {
+		log.Debug("rate limit pause", "duration", pause.Round(time.Millisecond))
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(pause):
+		}
+	}
+	return t.limiter.Wait(ctx)
+}
+
+// success records a healthy response (additive increase)
+func (t *RateLimiter) success() {
+	if !t.adaptive {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if limit := t.limiter.Limit(); limit < t.max {
+		t.limiter.SetLimit(min(limit+t.step, t.max))
+	}
+}
+
+// backoff records an overloaded response (multiplicative decrease)
+func (t *RateLimiter) backoff(log *slog.Logger) {
+	if !t.adaptive {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	limit := max(t.limiter.Limit()/2, t.min)
+	t.limiter.SetLimit(limit)
+	log.Debug("rate limit decreased", "rate", float64(limit))
+}
+
+// pause suspends all requests for d
+func (t *RateLimiter) pause(d time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if until := time.Now().Add(d); until.After(t.pausedUntil) {
+		t.pausedUntil = until
+	}
+}
+
+// observe pauses requests when rate-limit headers report an exhausted quota
+func (t *RateLimiter) observe(h http.Header, log *slog.Logger) {
+	remaining := firstHeader(h, "X-RateLimit-Remaining", "RateLimit-Remaining")
+	if remaining != "0" {
+		return
+	}
+	reset := firstHeader(h, "X-RateLimit-Reset", "RateLimit-Reset")
+	if d, ok := parseResetHeader(reset); ok {
+		log.Debug("rate limit quota exhausted", "reset_in", d)
+		t.pause(d)
+	}
+}
+
+func firstHeader(h http.Header, names ...string) string {
+	for _, n := range names {
+		if v := h.Get(n); v != "" {
+			return strings.TrimSpace(v)
+		}
+	}
+	return ""
+}
+
+// parseResetHeader parses a reset header given as delta seconds or Unix timestamp
+func parseResetHeader(v string) (time.Duration, bool) {
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || n < 0 {
+		return 0, false
+	}
+	if n > 1_000_000_000 { // Unix timestamp
+		d := time.Until(time.Unix(n, 0))
+		return d, d > 0
+	}
+	return time.Duration(n) * time.Second, true
+}
+
+// parseRetryAfter parses a Retry-After header (delta seconds or HTTP date)
+func parseRetryAfter(v string) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(strings.TrimSpace(v)); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		d := time.Until(t)
+		return d, d > 0
+	}
+	return 0, false
+}
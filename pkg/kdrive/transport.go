@@ -0,0 +1,435 @@
+package kdrive
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// traceBodyLimit is the number of body bytes printed per request/response when tracing
+const traceBodyLimit = 2048
+
+// recordTextLimit is the number of bytes of a non-JSON response body (file
+// downloads, CSV exports) kept in a fixture
+const recordTextLimit = 1 << 20
+
+// TransportOptions configures the network transport built by NewTransport
+type TransportOptions struct {
+	ProxyURL           string // http(s)://[user:pass@]host:port (default: HTTPS_PROXY/HTTP_PROXY)
+	CAFile             string // extra PEM CA bundle, added to the system pool
+	ClientCert         string // PEM client certificate (mutual TLS)
+	ClientKey          string // PEM client key
+	InsecureSkipVerify bool   // skip TLS verification (test stand-ins only)
+	MaxIdleConns       int    // idle connections kept per host
+	MaxConnsPerHost    int    // 0 = unlimited
+}
+
+// NewTransport builds a transport reaching the API with the given proxy,
+// TLS and connection pool settings.
+func NewTransport(opts TransportOptions) (*http.Transport, error) {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+
+	if opts.ProxyURL != "" {
+		proxy, err := url.Parse(opts.ProxyURL)
+		if err != nil || proxy.Host == "" {
+			return nil, fmt.Errorf("invalid proxy URL '%s'", opts.ProxyURL)
+		}
+		// Credentials in the URL are sent as Proxy-Authorization
+		t.Proxy = http.ProxyURL(proxy)
+	}
+
+	if opts.MaxIdleConns > 0 {
+		t.MaxIdleConnsPerHost = opts.MaxIdleConns
+		t.MaxIdleConns = max(t.MaxIdleConns, opts.MaxIdleConns)
+	}
+	t.MaxConnsPerHost = opts.MaxConnsPerHost
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if opts.CAFile != "" {
+		pem, err := os.ReadFile(opts.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("CA file: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("CA file: no certificate found in %s", opts.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if opts.ClientCert != "" {
+		cert, err := tls.LoadX509KeyPair(opts.ClientCert, opts.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	if opts.InsecureSkipVerify {
+		tlsConfig.InsecureSkipVerify = true
+	}
+	t.TLSClientConfig = tlsConfig
+
+	return t, nil
+}
+
+// NewTraceTransport wraps next to print every exchange on w: method, URL,
+// status, latency, rate limit headers and bodies with credentials redacted.
+func NewTraceTransport(next http.RoundTripper, w io.Writer) http.RoundTripper {
+	return &traceTransport{next: next, w: w}
+}
+
+// NewRecordTransport wraps next to save every exchange as a JSON fixture in dir,
+// appending to an existing recording. The Authorization header is never written.
+func NewRecordTransport(next http.RoundTripper, dir string) (http.RoundTripper, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("cannot create record directory: %w", err)
+	}
+	existing, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	return &recordTransport{next: next, dir: dir, seq: len(existing)}, nil
+}
+
+// NewReplayTransport serves the exchanges recorded in dir without network.
+// Requests are matched by method, request URI (host ignored) and body.
+func NewReplayTransport(dir string) (http.RoundTripper, error) {
+	return newReplayTransport(dir)
+}
+
+// readBody drains an optional body and returns its bytes with a fresh reader
+func readBody(body io.ReadCloser) ([]byte, io.ReadCloser, error) {
+	if body == nil || body == http.NoBody {
+		return nil, body, nil
+	}
+	data, err := io.ReadAll(body)
+	body.Close()
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, io.NopCloser(bytes.NewReader(data)), nil
+}
+
+// isJSON reports whether a response has a JSON body
+func isJSON(h http.Header) bool {
+	return strings.Contains(h.Get("Content-Type"), "json")
+}
+
+// peekBody reads at most limit bytes of a body and returns them with a reader
+// yielding the whole body, so large bodies stream through without being held
+// in memory. truncated is set when the body is longer than limit.
+func peekBody(body io.ReadCloser, limit int) (data []byte, r io.ReadCloser, truncated bool, err error) {
+	if body == nil || body == http.NoBody {
+		return nil, body, false, nil
+	}
+	data, err = io.ReadAll(io.LimitReader(body, int64(limit)+1))
+	if err != nil {
+		body.Close()
+		return nil, nil, false, err
+	}
+	if len(data) <= limit {
+		body.Close()
+		return data, io.NopCloser(bytes.NewReader(data)), false, nil
+	}
+	rest := struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(data), body), body}
+	return data[:limit], rest, true, nil
+}
+
+// traceTransport prints every exchange (method, URL, status, latency, redacted bodies)
+type traceTransport struct {
+	next http.RoundTripper
+	w    io.Writer
+	mu   sync.Mutex
+}
+
+func (t *traceTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	reqBody, body, err := readBody(req.Body)
+	if err != nil {
+		return nil, err
+	}
+	req.Body = body
+
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	elapsed := time.Since(start).Round(time.Millisecond)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "--> %s %s\n", req.Method, req.URL)
+	if auth := req.Header.Get("Authorization"); auth != "" {
+		fmt.Fprintf(&b, "    Authorization: %s\n", redactAuthorization(auth))
+	}
+	writeTraceBody(&b, reqBody)
+
+	if err != nil {
+		fmt.Fprintf(&b, "<-- error %s %s (%s): %v\n", req.Method, req.URL.Path, elapsed, err)
+		t.write(b.String())
+		return nil, err
+	}
+
+	// Non-JSON bodies (downloads) are not buffered nor printed
+	var respBody []byte
+	var size string
+	if isJSON(resp.Header) {
+		var body io.ReadCloser
+		var readErr error
+		if respBody, body, readErr = readBody(resp.Body); readErr != nil {
+			return nil, readErr
+		}
+		resp.Body = body
+		size = fmt.Sprintf("%d bytes", len(respBody))
+	} else {
+		size = "body not shown"
+		if ct := resp.Header.Get("Content-Type"); ct != "" {
+			size = ct + ", " + size
+		}
+		if resp.ContentLength >= 0 {
+			size = fmt.Sprintf("%d bytes, %s", resp.ContentLength, size)
+		}
+	}
+
+	fmt.Fprintf(&b, "<-- %d %s %s (%s, %s)\n", resp.StatusCode, req.Method, req.URL.Path, elapsed, size)
+	for _, name := range []string{"Retry-After", "X-RateLimit-Remaining", "X-RateLimit-Reset"} {
+		if v := resp.Header.Get(name); v != "" {
+			fmt.Fprintf(&b, "    %s: %s\n", name, v)
+		}
+	}
+	writeTraceBody(&b, respBody)
+	t.write(b.String())
+
+	return resp, nil
+}
+
+// write prints a whole exchange at once so concurrent requests do not interleave
+func (t *traceTransport) write(s string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	io.WriteString(t.w, s)
+}
+
+func writeTraceBody(b *strings.Builder, body []byte) {
+	if len(body) == 0 {
+		return
+	}
+	s := redactBody(body)
+	if len(s) > traceBodyLimit {
+		cut := traceBodyLimit
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut-- // do not split a multi-byte character
+		}
+		s = fmt.Sprintf("%s... (%d bytes truncated)", s[:cut], len(s)-cut)
+	}
+	fmt.Fprintf(b, "    %s\n", s)
+}
+
+func redactAuthorization(auth string) string {
+	scheme, _, ok := strings.Cut(auth, " ")
+	if !ok {
+		return "***"
+	}
+	return scheme + " ***"
+}
+
+var secretFieldRe = regexp.MustCompile(`("[^"]*(?i:token|password|secret)[^"]*"\s*:\s*)"[^"]*"`)
+
+// redactBody masks the values of JSON string fields that look like credentials
+func redactBody(body []byte) string {
+	return secretFieldRe.ReplaceAllString(string(body), `$1"***"`)
+}
+
+// exchange is an HTTP request/response pair saved by --record and served by --replay
+type exchange struct {
+	Method      string            `json:"method"`
+	URL         string            `json:"url"` // request URI (path and query)
+	RequestBody string            `json:"request_body,omitempty"`
+	Status      int               `json:"status"`
+	Header      map[string]string `json:"header,omitempty"`
+	Body        json.RawMessage   `json:"body,omitempty"`      // JSON response body
+	BodyText    string            `json:"body_text,omitempty"` // non-JSON response body
+	Truncated   bool              `json:"truncated,omitempty"` // BodyText cut at recordTextLimit
+}
+
+// key identifies an exchange by method, request URI and (redacted) request
+// body. The host is ignored so fixtures replay against any base_url.
+func (e *exchange) key() string {
+	sum := sha1.Sum([]byte(e.Method + " " + e.URL + "\n" + e.RequestBody))
+	return hex.EncodeToString(sum[:6])
+}
+
+func (e *exchange) body() []byte {
+	if e.Body != nil {
+		return e.Body
+	}
+	return []byte(e.BodyText)
+}
+
+// recordedHeaders are the response headers kept in fixtures
+var recordedHeaders = []string{"Content-Type", "Retry-After", "X-RateLimit-Remaining", "X-RateLimit-Reset"}
+
+// recordTransport saves every exchange as a JSON fixture in dir.
+// The Authorization header is never written and credentials in request bodies
+// are masked (redactBody). Non-JSON response bodies are kept up to
+// recordTextLimit bytes.
+type recordTransport struct {
+	next http.RoundTripper
+	dir  string
+
+	mu  sync.Mutex
+	seq int
+}
+
+func (t *recordTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	reqBody, body, err := readBody(req.Body)
+	if err != nil {
+		return nil, err
+	}
+	req.Body = body
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var respBody []byte
+	truncated := false
+	if isJSON(resp.Header) {
+		respBody, body, err = readBody(resp.Body)
+	} else {
+		respBody, body, truncated, err = peekBody(resp.Body, recordTextLimit)
+	}
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = body
+
+	e := exchange{
+		Method:      req.Method,
+		URL:         req.URL.RequestURI(),
+		RequestBody: redactBody(reqBody),
+		Status:      resp.StatusCode,
+		Header:      map[string]string{},
+		Truncated:   truncated,
+	}
+	for _, name := range recordedHeaders {
+		if v := resp.Header.Get(name); v != "" {
+			e.Header[name] = v
+		}
+	}
+	if !truncated && json.Valid(respBody) {
+		e.Body = respBody
+	} else {
+		e.BodyText = string(respBody)
+	}
+
+	if err := t.save(&e); err != nil {
+		return nil, fmt.Errorf("record: %w", err)
+	}
+	return resp, nil
+}
+
+func (t *recordTransport) save(e *exchange) error {
+	data, err := json.MarshalIndent(e, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	t.seq++
+	name := fmt.Sprintf("%05d-%s-%s.json", t.seq, strings.ToLower(e.Method), e.key())
+	t.mu.Unlock()
+
+	return os.WriteFile(filepath.Join(t.dir, name), data, 0600)
+}
+
+// replayTransport serves exchanges recorded by recordTransport without network.
+// Identical requests get their recorded responses in order; the last one is
+// repeated once they are exhausted.
+type replayTransport struct {
+	mu        sync.Mutex
+	exchanges map[string][]*exchange
+}
+
+func newReplayTransport(dir string) (*replayTransport, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no recorded exchanges in %s", dir)
+	}
+	sort.Strings(paths) // recording order
+
+	t := &replayTransport{exchanges: make(map[string][]*exchange)}
+	for _, p := range paths {
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return nil, err
+		}
+		var e exchange
+		if err := json.Unmarshal(data, &e); err != nil {
+			return nil, fmt.Errorf("invalid fixture %s: %w", p, err)
+		}
+		k := e.key()
+		t.exchanges[k] = append(t.exchanges[k], &e)
+	}
+	return t, nil
+}
+
+func (t *replayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	reqBody, _, err := readBody(req.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	// Fixtures hold redacted bodies: match on the redacted form
+	wanted := exchange{Method: req.Method, URL: req.URL.RequestURI(), RequestBody: redactBody(reqBody)}
+	k := wanted.key()
+
+	t.mu.Lock()
+	queue := t.exchanges[k]
+	var e *exchange
+	if len(queue) > 0 {
+		e = queue[0]
+		if len(queue) > 1 {
+			t.exchanges[k] = queue[1:]
+		}
+	}
+	t.mu.Unlock()
+
+	if e == nil {
+		return nil, fmt.Errorf("replay: no recorded response for %s %s", req.Method, wanted.URL)
+	}
+
+	header := make(http.Header)
+	for name, v := range e.Header {
+		header.Set(name, v)
+	}
+	body := e.body()
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", e.Status, http.StatusText(e.Status)),
+		StatusCode:    e.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
@@ -0,0 +1,81 @@
+package kdrive
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// DriveUser is a user of the drive
+type DriveUser struct {
+	ID               int    `json:"id"`
+	DisplayName      string `json:"display_name"`
+	Email            string `json:"email"`
+	Role             string `json:"role"`   // admin, user, external
+	Status           string `json:"status"` // active, pending, locked...
+	CreatedAt        int64  `json:"created_at"`
+	LastConnectionAt int64  `json:"last_connection_at"`
+}
+
+// Team is a group of drive users that can be granted access as a whole
+type Team struct {
+	ID      int    `json:"id"`
+	Name    string `json:"name"`
+	Color   string `json:"color,omitempty"`
+	UserIDs []int  `json:"user_ids"`
+}
+
+// ListUsers returns a page of drive users (page starts at 1) and the number of pages
+func (c *Client) ListUsers(ctx context.Context, page int) ([]DriveUser, int, error) {
+	q := url.Values{}
+	q.Set("limit", "1000")
+	if page > 1 {
+		q.Set("page", strconv.Itoa(page))
+	}
+	path := fmt.Sprintf("/2/drive/%d/users?%s", c.driveID, q.Encode())
+	resp, err := callPage[[]DriveUser](ctx, c, http.MethodGet, path, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	return resp.Data, resp.Pages, nil
+}
+
+// Users iterates over every user of the drive, page by page
+func (c *Client) Users(ctx context.Context) iter.Seq2[DriveUser, error] {
+	return func(yield func(DriveUser, error) bool) {
+		for page := 1; ; page++ {
+			if err := ctx.Err(); err != nil {
+				yield(DriveUser{}, err)
+				return
+			}
+			users, pages, err := c.ListUsers(ctx, page)
+			if err != nil {
+				yield(DriveUser{}, err)
+				return
+			}
+			for _, u := range users {
+				if !yield(u, nil) {
+					return
+				}
+			}
+			if page >= pages {
+				return
+			}
+		}
+	}
+}
+
+// GetUser returns a drive user
+func (c *Client) GetUser(ctx context.Context, userID int) (*DriveUser, error) {
+	path := fmt.Sprintf("/2/drive/%d/users/%d", c.driveID, userID)
+	return call[*DriveUser](ctx, c, http.MethodGet, path, nil)
+}
+
+// ListTeams returns the teams of the drive with their members
+func (c *Client) ListTeams(ctx context.Context) ([]Team, error) {
+	path := fmt.Sprintf("/2/drive/%d/teams", c.driveID)
+	return call[[]Team](ctx, c, http.MethodGet, path, nil)
+}
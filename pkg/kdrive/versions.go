@@ -0,0 +1,91 @@
+package kdrive
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+)
+
+// Version is a stored version of a file
+type Version struct {
+	ID          int   `json:"id"`
+	Size        int64 `json:"size"`
+	CreatedAt   int64 `json:"created_at"`
+	UserID      int   `json:"user_id"`      // author of the version
+	KeepForever bool  `json:"keep_forever"` // protected from automatic cleanup
+}
+
+// ListVersions returns the versions of a file, newest first. The newest one
+// is the current content of the file.
+func (c *Client) ListVersions(ctx context.Context, fileID int) ([]Version, error) {
+	path := fmt.Sprintf("/2/drive/%d/files/%d/versions", c.driveID, fileID)
+	versions, err := call[[]Version](ctx, c, http.MethodGet, path, nil)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(versions, func(i, j int) bool {
+		return versions[i].CreatedAt > versions[j].CreatedAt
+	})
+	return versions, nil
+}
+
+// FileVersions lists the versions of several files concurrently, like
+// FileAccesses: files whose versions cannot be listed are reported in the
+// joined error.
+func (c *Client) FileVersions(ctx context.Context, fileIDs []int, progress func(done, total int)) (map[int][]Version, error) {
+	return fetchEach(ctx, c, fileIDs, "versions", c.ListVersions, progress)
+}
+
+// DownloadVersion writes the content of a version to w and returns the number
+// of bytes written. The download is not retried.
+func (c *Client) DownloadVersion(ctx context.Context, fileID, versionID int, w io.Writer) (int64, error) {
+	path := fmt.Sprintf("/2/drive/%d/files/%d/versions/%d/download", c.driveID, fileID, versionID)
+	if err := c.throttle.wait(ctx, c.logger); err != nil {
+		return 0, fmt.Errorf("rate limiter: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+path, nil)
+	if err != nil {
+		return 0, fmt.Errorf("request creation error: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set("User-Agent", c.userAgent)
+
+	// Same transport as API calls, without the 30s client timeout (files can be large)
+	downloadClient := &http.Client{Transport: c.httpClient.Transport}
+	resp, err := downloadClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("HTTP request error: %w", err)
+	}
+	defer resp.Body.Close()
+
+	c.throttle.observe(resp.Header, c.logger)
+	if resp.StatusCode >= 400 {
+		body, _ := io.ReadAll(resp.Body)
+		return 0, newError(http.MethodGet, path, resp.StatusCode, body)
+	}
+
+	n, err := io.Copy(w, resp.Body)
+	if err != nil {
+		return n, fmt.Errorf("download error: %w", err)
+	}
+	return n, nil
+}
+
+// RestoreVersion makes a version the current content of its file (the
+// replaced content is kept as a new version)
+func (c *Client) RestoreVersion(ctx context.Context, fileID, versionID int) error {
+	path := fmt.Sprintf("/2/drive/%d/files/%d/versions/%d/restore", c.driveID, fileID, versionID)
+	_, err := call[json.RawMessage](ctx, c, http.MethodPost, path, nil)
+	return err
+}
+
+// DeleteVersion deletes a stored version of a file
+func (c *Client) DeleteVersion(ctx context.Context, fileID, versionID int) error {
+	path := fmt.Sprintf("/2/drive/%d/files/%d/versions/%d", c.driveID, fileID, versionID)
+	_, err := call[bool](ctx, c, http.MethodDelete, path, nil)
+	return err
+}
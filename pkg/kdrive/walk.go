@@ -0,0 +1,352 @@
+package kdrive
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"strings"
+	"time"
+)
+
+// ProgressCallback is called during recursive operations to report progress
+type ProgressCallback func(dirName string, fileCount int)
+
+// WalkDir is a directory queued for listing during a recursive walk
+type WalkDir struct {
+	ID    int    `json:"id"`
+	Name  string `json:"name"`
+	Depth int    `json:"depth,omitempty"` // relative to the walk root (0)
+	Path  string `json:"path,omitempty"`
+}
+
+// WalkState is a resumable snapshot of a recursive listing: the entries listed
+// so far, the frontier of directories not listed yet and the directories that
+// failed. Resuming a walk lists both Pending and Failed directories.
+// Files is only populated by ListFilesRecursiveWithOptions: streaming walks
+// (Walk) leave it to the callback to persist its own progress.
+type WalkState struct {
+	Files   []File    `json:"files"`
+	Pending []WalkDir `json:"pending"`
+	Failed  []WalkDir `json:"failed,omitempty"`
+}
+
+// DirError is a directory that could not be listed
+type DirError struct {
+	ID   int
+	Name string
+	Err  error
+}
+
+func (e *DirError) Error() string {
+	return fmt.Sprintf("listing %s (%d): %v", e.Name, e.ID, e.Err)
+}
+
+func (e *DirError) Unwrap() error {
+	return e.Err
+}
+
+// PartialError is returned alongside a partial listing when some directories
+// could not be listed (their subtrees are missing from the result).
+type PartialError struct {
+	Dirs []DirError
+}
+
+func (e *PartialError) Error() string {
+	return fmt.Sprintf("%d directories could not be listed (first: %v)", len(e.Dirs), &e.Dirs[0])
+}
+
+func (e *PartialError) Unwrap() []error {
+	errs := make([]error, len(e.Dirs))
+	for i := range e.Dirs {
+		errs[i] = &e.Dirs[i]
+	}
+	return errs
+}
+
+// WalkOrder is the order in which directories are listed
+type WalkOrder int
+
+const (
+	// BreadthFirst lists directories level by level (default)
+	BreadthFirst WalkOrder = iota
+	// DepthFirst lists the most recently discovered directories first, which
+	// keeps the frontier small on wide trees
+	DepthFirst
+)
+
+// SkipAll can be returned by a Walk callback to stop the walk without error
+var SkipAll = errors.New("skip remaining files")
+
+// WalkOptions controls a recursive listing
+type WalkOptions struct {
+	// WithCategories populates the categories of every listed file
+	WithCategories bool
+	// With requests extra fields for every listed file (API "with" values,
+	// e.g. "sharelink")
+	With []string
+
+	// MaxDepth limits the listing to MaxDepth levels below the root
+	// (1 = direct children only, 0 = unlimited)
+	MaxDepth int
+	// Prune is called for every directory found; returning true skips its
+	// subtree (the directory itself is still reported)
+	Prune func(dir *File) bool
+	// Order of the directory listings (with several workers, the order is approximate)
+	Order WalkOrder
+	// RootPath is the drive path of the walk root (default: resolved with FilePath)
+	RootPath string
+
+	// Strict fails the whole walk (returning no files) if any directory cannot
+	// be listed. Otherwise the partial listing is returned with a *PartialError.
+	Strict bool
+	// Retries is the number of extra attempts for a failing directory (default 2, -1 disables)
+	Retries int
+
+	// State resumes a previous walk when non-nil
+	State *WalkState
+	// Checkpoint receives snapshots periodically and when the walk stops early or partially
+	Checkpoint func(*WalkState)
+	// Interval between periodic checkpoints (default 30s)
+	Interval time.Duration
+}
+
+// ListFilesRecursive lists all files in a directory and its subdirectories
+func (c *Client) ListFilesRecursive(ctx context.Context, fileID int) ([]File, error) {
+	return c.ListFilesRecursiveWithProgress(ctx, fileID, "", nil)
+}
+
+// ListFilesRecursiveWithProgress lists all files with a progress callback.
+// rootName is used for progress display (pass empty string to use "root").
+// It fails if any directory cannot be listed.
+func (c *Client) ListFilesRecursiveWithProgress(ctx context.Context, fileID int, rootName string, progress ProgressCallback) ([]File, error) {
+	return c.ListFilesRecursiveWithOptions(ctx, fileID, rootName, progress, WalkOptions{Strict: true})
+}
+
+// ListFilesRecursiveWithCategories is like ListFilesRecursiveWithProgress but
+// populates the categories of every listed file.
+func (c *Client) ListFilesRecursiveWithCategories(ctx context.Context, fileID int, rootName string, progress ProgressCallback) ([]File, error) {
+	return c.ListFilesRecursiveWithOptions(ctx, fileID, rootName, progress, WalkOptions{Strict: true, WithCategories: true})
+}
+
+// ListFilesRecursiveWithOptions lists all files below fileID. Unless opts.Strict
+// is set, directories that keep failing after retries are skipped: the listing
+// of everything else is returned together with a *PartialError.
+func (c *Client) ListFilesRecursiveWithOptions(ctx context.Context, fileID int, rootName string, progress ProgressCallback, opts WalkOptions) ([]File, error) {
+	var allFiles []File
+	if opts.State != nil {
+		allFiles = append(allFiles, opts.State.Files...)
+	}
+
+	// Snapshots carry the files collected so far
+	if checkpoint := opts.Checkpoint; checkpoint != nil {
+		opts.Checkpoint = func(s *WalkState) {
+			s.Files = allFiles
+			checkpoint(s)
+		}
+	}
+
+	err := c.Walk(ctx, fileID, rootName, progress, opts, func(f *File) error {
+		allFiles = append(allFiles, *f)
+		return nil
+	})
+
+	var partial *PartialError
+	if err != nil && !errors.As(err, &partial) {
+		return nil, err
+	}
+	return allFiles, err
+}
+
+// Walk lists every file below fileID, calling fn for each of them as soon as
+// its directory is listed, so the tree never has to fit in memory. fn is called
+// from a single goroutine. If fn returns SkipAll the walk stops without error;
+// any other error stops it and is returned.
+//
+// Every file gets its drive Path, and listed directories are added to the
+// client path index (Paths).
+//
+// Unless opts.Strict is set, directories that keep failing after retries are
+// skipped and a *PartialError is returned once the rest of the tree is walked.
+// Resuming from opts.State only lists the Pending and Failed directories: the
+// callback is responsible for persisting what it aggregated (see Checkpoint,
+// which is always called between two fn calls).
+func (c *Client) Walk(ctx context.Context, fileID int, rootName string, progress ProgressCallback, opts WalkOptions, fn func(*File) error) error {
+	if rootName == "" {
+		rootName = "root"
+	}
+	with := opts.With
+	if opts.WithCategories {
+		with = append([]string{"file.categories"}, with...)
+	}
+	list := func(ctx context.Context, dirID int) iter.Seq2[File, error] {
+		return c.files(ctx, dirID, strings.Join(with, ","))
+	}
+	retries := opts.Retries
+	if retries == 0 {
+		retries = 2
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		dir   WalkDir
+		files []File
+		err   error
+	}
+
+	jobs := make(chan WalkDir)
+	results := make(chan result)
+
+	// Workers exit when jobs is closed or context is cancelled
+	for i := 0; i < c.workers; i++ {
+		go func() {
+			for j := range jobs {
+				files, err := Collect(list(ctx, j.ID))
+				if ctx.Err() != nil {
+					return
+				}
+				select {
+				case results <- result{dir: j, files: files, err: err}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+	defer close(jobs)
+
+	var queue []WalkDir
+	if opts.State != nil {
+		queue = append(queue, opts.State.Pending...)
+		queue = append(queue, opts.State.Failed...)
+	} else {
+		rootPath := opts.RootPath
+		if rootPath == "" {
+			var err error
+			if rootPath, err = c.FilePath(ctx, fileID); err != nil {
+				return err
+			}
+		}
+		queue = append(queue, WalkDir{ID: fileID, Name: rootName, Path: CleanPath(rootPath)})
+	}
+
+	// outstanding holds every directory queued or being listed, i.e. the frontier
+	outstanding := make(map[int]WalkDir, len(queue))
+	for _, d := range queue {
+		outstanding[d.ID] = d
+	}
+	attempts := make(map[int]int)
+	var failed []DirError
+	var failedDirs []WalkDir
+	count := 0
+
+	snapshot := func() {
+		if opts.Checkpoint == nil {
+			return
+		}
+		state := &WalkState{}
+		for _, d := range outstanding {
+			state.Pending = append(state.Pending, d)
+		}
+		state.Failed = append(state.Failed, failedDirs...)
+		opts.Checkpoint(state)
+	}
+	interval := opts.Interval
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	lastCheckpoint := time.Now()
+
+	inFlight := 0
+
+	for len(queue) > 0 || inFlight > 0 {
+		// Only offer a job when one is queued (a nil channel blocks forever)
+		var send chan WalkDir
+		var next WalkDir
+		if len(queue) > 0 {
+			send = jobs
+			if opts.Order == DepthFirst {
+				next = queue[len(queue)-1]
+			} else {
+				next = queue[0]
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			snapshot()
+			return ctx.Err()
+
+		case send <- next:
+			if opts.Order == DepthFirst {
+				queue = queue[:len(queue)-1]
+			} else {
+				queue = queue[1:]
+			}
+			inFlight++
+
+		case r := <-results:
+			inFlight--
+
+			if r.err != nil {
+				attempts[r.dir.ID]++
+				if retries > 0 && attempts[r.dir.ID] <= retries {
+					c.logger.Debug("listing failed, retrying later", "dir", r.dir.Name, "id", r.dir.ID, "err", r.err)
+					queue = append(queue, r.dir)
+					continue
+				}
+				delete(outstanding, r.dir.ID)
+				failed = append(failed, DirError{ID: r.dir.ID, Name: r.dir.Name, Err: r.err})
+				failedDirs = append(failedDirs, r.dir)
+				continue
+			}
+			delete(outstanding, r.dir.ID)
+
+			for i := range r.files {
+				f := &r.files[i]
+				f.Path = JoinPath(r.dir.Path, f.Name)
+				if f.Type == "dir" {
+					c.paths.AddFile(f)
+				}
+				if f.Type == "dir" && (opts.MaxDepth <= 0 || r.dir.Depth+1 < opts.MaxDepth) {
+					if opts.Prune != nil && opts.Prune(f) {
+						c.logger.Debug("pruned directory", "dir", f.Name, "id", f.ID)
+					} else {
+						d := WalkDir{ID: f.ID, Name: f.Name, Depth: r.dir.Depth + 1, Path: f.Path}
+						queue = append(queue, d)
+						outstanding[d.ID] = d
+					}
+				}
+
+				if err := fn(f); err != nil {
+					if err == SkipAll {
+						return nil
+					}
+					return err
+				}
+				count++
+			}
+
+			if progress != nil {
+				progress(r.dir.Name, count)
+			}
+
+			if time.Since(lastCheckpoint) >= interval {
+				snapshot()
+				lastCheckpoint = time.Now()
+			}
+		}
+	}
+
+	if len(failed) > 0 {
+		// Failed directories are kept in the snapshot so a resumed walk retries them
+		snapshot()
+		if opts.Strict {
+			return &failed[0]
+		}
+		return &PartialError{Dirs: failed}
+	}
+
+	return nil
+}